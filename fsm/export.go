@@ -0,0 +1,204 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DOT renders states as a Graphviz digraph, composite states are clusters and edges are labelled
+// with their event, the current and previous states of m are highlighted when m is not nil.
+func DOT(states States, m *Machine) string {
+	g := newGraph(states, m)
+	b := &strings.Builder{}
+	b.WriteString("digraph fsm {\n\tcompound=true;\n\tnode [shape=box, style=rounded];\n")
+	g.dotStates(b, "", "\t")
+	for _, e := range g.edges() {
+		attrs := []string{"label=" + dotQuote(e.label)}
+		if g.composite(e.from) {
+			attrs = append(attrs, "ltail="+dotQuote("cluster_"+string(e.from)))
+		}
+		if g.composite(e.to) {
+			attrs = append(attrs, "lhead="+dotQuote("cluster_"+string(e.to)))
+		}
+		fmt.Fprintf(b, "\t%s -> %s [%s];\n", dotQuote(string(g.leaf(e.from))), dotQuote(string(g.leaf(e.to))), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *graph) dotStates(b *strings.Builder, parent StateType, indent string) {
+	for _, state := range g.children[parent] {
+		if !g.composite(state) {
+			switch state {
+			case g.curr:
+				fmt.Fprintf(b, "%s%s [style=\"rounded,filled,bold\", fillcolor=\"lightblue\"];\n", indent, dotQuote(string(state)))
+			case g.prev:
+				fmt.Fprintf(b, "%s%s [style=\"rounded,dashed\"];\n", indent, dotQuote(string(state)))
+			default:
+				fmt.Fprintf(b, "%s%s;\n", indent, dotQuote(string(state)))
+			}
+			continue
+		}
+		fmt.Fprintf(b, "%ssubgraph %s {\n%s\tlabel=%s;\n", indent, dotQuote("cluster_"+string(state)), indent, dotQuote(g.label(state)))
+		g.dotStates(b, state, indent+"\t")
+		fmt.Fprintf(b, "%s}\n", indent)
+	}
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Mermaid renders states as a Mermaid stateDiagram-v2, composite states enclose their children
+// and edges are labelled with their event, the current and previous states of m are highlighted
+// when m is not nil.
+func Mermaid(states States, m *Machine) string {
+	g := newGraph(states, m)
+	b := &strings.Builder{}
+	b.WriteString("stateDiagram-v2\n")
+	g.mermaidStates(b, "", "\t")
+	for _, e := range g.edges() {
+		fmt.Fprintf(b, "\t%s --> %s : %s\n", g.ids[e.from], g.ids[e.to], mermaidEscape(e.label))
+	}
+	if g.curr != "" {
+		fmt.Fprintf(b, "\tclassDef current font-weight:bold,fill:lightblue\n\tclass %s current\n", g.ids[g.curr])
+	}
+	if g.prev != "" && g.prev != g.curr {
+		fmt.Fprintf(b, "\tclassDef previous stroke-dasharray:5 5\n\tclass %s previous\n", g.ids[g.prev])
+	}
+	return b.String()
+}
+
+func (g *graph) mermaidStates(b *strings.Builder, parent StateType, indent string) {
+	for _, state := range g.children[parent] {
+		fmt.Fprintf(b, "%sstate \"%s\" as %s\n", indent, mermaidEscape(g.label(state)), g.ids[state])
+		if !g.composite(state) {
+			continue
+		}
+		fmt.Fprintf(b, "%sstate %s {\n", indent, g.ids[state])
+		if initial := g.states[state].Initial; initial != "" {
+			fmt.Fprintf(b, "%s\t[*] --> %s\n", indent, g.ids[initial])
+		}
+		g.mermaidStates(b, state, indent+"\t")
+		fmt.Fprintf(b, "%s}\n", indent)
+	}
+}
+
+func mermaidEscape(s string) string { return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) }
+
+// graph is the deterministic layout of States shared by the exporters,
+// states are sorted by name and edges by source, event and order of transitions.
+type graph struct {
+	states     States
+	names      []StateType               // names of the states and of their targets, sorted.
+	children   map[StateType][]StateType // children of each state, "" holds the outermost states.
+	ids        map[StateType]string      // ids of each state in sorted order, for Mermaid.
+	curr, prev StateType
+}
+
+type graphEdge struct {
+	from, to StateType
+	label    string
+}
+
+func newGraph(states States, m *Machine) *graph {
+	g := &graph{states: states, children: map[StateType][]StateType{}, ids: map[StateType]string{}}
+	if m != nil {
+		g.prev, g.curr = m.GetStates()
+	}
+	// targets which are not defined are drawn as outermost states
+	names := map[StateType]bool{}
+	for name, state := range states {
+		names[name], names[state.Initial] = true, true
+		for _, to := range state.Events {
+			names[to] = true
+		}
+		for _, transitions := range state.Transitions {
+			for _, t := range transitions {
+				names[t.Target] = true
+			}
+		}
+	}
+	delete(names, "")
+	for name := range names {
+		g.names = append(g.names, name)
+	}
+	sort.Slice(g.names, func(i, j int) bool { return g.names[i] < g.names[j] })
+	for i, name := range g.names {
+		g.ids[name] = fmt.Sprintf("s%d", i)
+		parent := states[name].Parent
+		if _, ok := states[parent]; !ok {
+			parent = ""
+		}
+		g.children[parent] = append(g.children[parent], name)
+	}
+	return g
+}
+
+// composite reports whether a state has children or an Initial one.
+func (g *graph) composite(state StateType) bool {
+	return len(g.children[state]) > 0 || g.states[state].Initial != ""
+}
+
+// label names a state, suffixed by (H) when it restores its history.
+func (g *graph) label(state StateType) string {
+	if g.states[state].History {
+		return string(state) + " (H)"
+	}
+	return string(state)
+}
+
+// leaf resolves the leaf drawn in place of a composite state, its Initial one or its first child.
+func (g *graph) leaf(state StateType) StateType {
+	for range g.states {
+		child := g.states[state].Initial
+		if child == "" && len(g.children[state]) > 0 {
+			child = g.children[state][0]
+		}
+		if child == "" {
+			break
+		}
+		state = child
+	}
+	return state
+}
+
+// edges lists the guarded transitions of each event before its fallback in Events,
+// guards are labelled by their Name, if any.
+func (g *graph) edges() []graphEdge {
+	var edges []graphEdge
+	for _, from := range g.names {
+		state := g.states[from]
+		events := make([]EventType, 0, len(state.Events)+len(state.Transitions))
+		for event := range state.Transitions {
+			events = append(events, event)
+		}
+		for event := range state.Events {
+			if _, ok := state.Transitions[event]; !ok {
+				events = append(events, event)
+			}
+		}
+		sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+		for _, event := range events {
+			for _, t := range state.Transitions[event] {
+				label := string(event)
+				if t.Name != "" {
+					label += " [" + t.Name + "]"
+				}
+				edges = append(edges, graphEdge{from: from, to: t.Target, label: label})
+			}
+			if to, ok := state.Events[event]; ok {
+				edges = append(edges, graphEdge{from: from, to: to, label: string(event)})
+			}
+		}
+	}
+	// an edge without target is never taken
+	n := 0
+	for _, e := range edges {
+		if e.to != "" {
+			edges[n], n = e, n+1
+		}
+	}
+	return edges[:n]
+}
@@ -0,0 +1,67 @@
+package fsm_test
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hokonco/kitgo/fsm"
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func Test_pkg_fsm_export(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	golden := func(name, actual string) {
+		path := filepath.Join("testdata", name)
+		if *update {
+			Expect(ioutil.WriteFile(path, []byte(actual), 0644)).To(Succeed())
+		}
+		expected, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(string(expected)), name)
+	}
+
+	light := fsm.States{
+		StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventOn: StateOn, EventBroken: StateBroken}},
+		StateOn:  fsm.State{Action: &OnAction{}, Events: fsm.Events{EventOff: StateOff, EventBroken: StateBroken}},
+	}
+	m := fsm.New(StateOff, light)
+	Expect(m.SendEvent(context.Background(), EventOn)).To(Succeed())
+	golden("light.dot", fsm.DOT(light, m))
+	golden("light.mmd", fsm.Mermaid(light, m))
+
+	never := func(context.Context) bool { return false }
+	order := fsm.States{
+		StateOrdered: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventShip: StateShipping}, Transitions: fsm.Transitions{
+			EventCancel: {{Name: `"unpaid"`, Guard: never, Target: StateCancelled}, {Name: "never"}},
+		}},
+		StateShipping:  fsm.State{Initial: StatePacked, History: true, Events: fsm.Events{EventCancel: StateCancelled, EventHold: StateOnHold}},
+		StatePacked:    fsm.State{Action: &OffAction{}, Parent: StateShipping, Events: fsm.Events{EventDispatch: StateInTransit}},
+		StateInTransit: fsm.State{Action: &OffAction{}, Parent: StateShipping, Events: fsm.Events{EventDispatch: StateShipping}},
+		StateOnHold:    fsm.State{Action: &OffAction{}, Events: fsm.Events{EventResume: StateShipping}},
+		StateCancelled: fsm.State{Action: &OffAction{}},
+	}
+	golden("order.dot", fsm.DOT(order, nil))
+	golden("order.mmd", fsm.Mermaid(order, nil))
+
+	// composite states without Initial are drawn from their first child
+	Expect(fsm.DOT(fsm.States{
+		"a": fsm.State{Events: fsm.Events{"e": "b"}},
+		"b": fsm.State{Parent: "a"},
+	}, fsm.New("a", nil))).To(Equal(`digraph fsm {
+	compound=true;
+	node [shape=box, style=rounded];
+	subgraph "cluster_a" {
+		label="a";
+		"b";
+	}
+	"b" -> "b" [label="e", ltail="cluster_a"];
+}
+`))
+}
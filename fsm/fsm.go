@@ -3,7 +3,17 @@ package fsm // https://venilnoronha.io/a-simple-state-machine-framework-in-go
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
+)
+
+var (
+	// ErrRejectedEvent is returned when the current state does not handle an event.
+	ErrRejectedEvent = errors.New("fsm: rejected event")
+	// ErrInvalidState is returned when the target of an event is undefined or has no Action.
+	ErrInvalidState = errors.New("fsm: invalid state")
 )
 
 // StateType represents an extensible state type in the state machine.
@@ -17,13 +27,80 @@ type Action interface {
 	Execute(ctx context.Context) EventType
 }
 
+// ActionFunc is an Action which can fail, SendEvent returns its error.
+type ActionFunc func(ctx context.Context) (EventType, error)
+
+// Execute implements Action, SendEvent calls Run instead so the error is not lost.
+func (f ActionFunc) Execute(ctx context.Context) EventType { event, _ := f(ctx); return event }
+
+// Run executes the action.
+func (f ActionFunc) Run(ctx context.Context) (EventType, error) { return f(ctx) }
+
+// ActionRunner is an Action which can fail, such as ActionFunc.
+type ActionRunner interface {
+	Action
+	Run(ctx context.Context) (EventType, error)
+}
+
+// Hook is called on entering or exiting a state.
+type Hook func(ctx context.Context) error
+
+// TransitionHook is called around the transition from one state to another on event.
+type TransitionHook func(ctx context.Context, event EventType, from, to StateType) error
+
 // Events represents a mapping of events and states.
 type Events map[EventType]StateType
 
+// GuardFunc reports whether a guarded transition can be taken, given the context passed to SendEvent.
+type GuardFunc func(ctx context.Context) bool
+
+// Transition is a target state guarded by Guard, a nil Guard always passes.
+type Transition struct {
+	Name   string // Name identifies the guard in GuardError, default to Target.
+	Guard  GuardFunc
+	Target StateType
+}
+
+// Transitions represents a mapping of events and ordered guarded transitions, the first passing one is taken.
+type Transitions map[EventType][]Transition
+
+// GuardError is returned when no guard of an event passes and Events has no fallback for it.
+type GuardError struct {
+	State  StateType
+	Event  EventType
+	Guards []string // Guards are the names of the failed guards, in order.
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("fsm: event %q rejected in state %q by guards: %s", e.Event, e.State, strings.Join(e.Guards, ", "))
+}
+
+// Unwrap allows errors.Is(err, ErrRejectedEvent).
+func (e *GuardError) Unwrap() error { return ErrRejectedEvent }
+
 // State binds a state with an action and a set of events it can handle.
+// Transitions of an event are evaluated before Events, which is then the unguarded fallback.
+// OnExit is called before leaving the state, an error cancels the transition,
+// OnEnter is called after entering the state, an error is returned by SendEvent.
+//
+// A state with a Parent is nested in it, events not handled by a state bubble up to its ancestors.
+// Entering a composite state enters its Initial child, or its last active child when History is set,
+// only the innermost (leaf) state needs an Action.
+//
+// Timeouts fire their Event after their duration is spent in the state, they are cancelled when the state is left.
 type State struct {
 	Action
 	Events
+	Transitions
+
+	OnEnter Hook
+	OnExit  Hook
+
+	Parent  StateType
+	Initial StateType
+	History bool
+
+	Timeouts []Timeout
 }
 
 // States represents a mapping of states and their implementations.
@@ -31,51 +108,198 @@ type States map[StateType]State
 
 // Machine represents the state machine.
 type Machine struct {
-	mu     sync.Mutex // mutex ensures that only 1 event is processed by the state machine at any given time.
-	prev   StateType  // Previous represents the previous state.
-	curr   StateType  // Current represents the current state.
-	states States     // States holds the configuration of states and events handled by the state machine.
+	mu      sync.Mutex              // mutex ensures that only 1 event is processed by the state machine at any given time.
+	prev    StateType               // Previous represents the previous state.
+	curr    StateType               // Current represents the current state.
+	states  States                  // States holds the configuration of states and events handled by the state machine.
+	history map[StateType]StateType // history holds the last active child of the composite states.
+	version int64                   // version of the last Snapshot saved or restored.
+	mailbox mailbox                 // mailbox queues the events of Post, see Start.
+	clock   Clock                   // clock schedules the timeouts, see SetClock.
+	entered map[StateType]time.Time // entered holds when each active state was entered.
+	timers  map[StateType]*[]Timer  // timers holds the timeouts scheduled for each active state.
 
 	// OnTransition called when transitioning from current StateType to the nextStateType
 	OnTransition func(curr, next StateType)
+
+	// BeforeTransition is called before OnExit, an error cancels the transition and leaves the states untouched.
+	BeforeTransition TransitionHook
+
+	// AfterTransition is called after OnEnter and before the Action, an error is returned by SendEvent.
+	AfterTransition TransitionHook
+
+	// OnTimeout is called with the result of SendEvent for each timeout event fired.
+	OnTimeout func(event EventType, err error)
 }
 
-// New create new finite-state Machine with initial StateType and States mapping.
-func New(curr StateType, states States) *Machine { return &Machine{curr: curr, states: states} }
+// New create new finite-state Machine with initial StateType and States mapping,
+// a composite initial StateType is entered down to its initial leaf.
+func New(curr StateType, states States) *Machine {
+	s := &Machine{states: states, history: map[StateType]StateType{}, clock: realClock{}}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-// nextState get next StateType and State, return error on invalid State.
-func (s *Machine) nextState(event EventType) (StateType, *State, error) {
-	if state, ok := s.states[s.curr]; ok && len(state.Events) > 0 {
-		if next, ok := state.Events[event]; ok {
-			if state, ok = s.states[next]; ok && state.Action != nil {
-				return next, &state, nil
+	s.activate(s.enter(curr))
+	s.arm(s.clock.Now(), nil, s.path(s.curr)...)
+	return s
+}
+
+// activate sets the current state, each of its ancestors remembers it as the last active child.
+func (s *Machine) activate(curr StateType) {
+	s.curr = curr
+	path := s.path(curr)
+	for i := 1; i < len(path); i++ {
+		s.history[path[i-1]] = path[i]
+	}
+}
+
+// nextState get next leaf StateType, the target of the event and the leaf State,
+// the event bubbles up from the current state to its ancestors, return error on invalid State.
+func (s *Machine) nextState(ctx context.Context, event EventType) (StateType, StateType, *State, error) {
+	var guardErr error
+	path := s.path(s.curr)
+	for i := len(path) - 1; i >= 0; i-- {
+		state, ok := s.states[path[i]]
+		if !ok {
+			break
+		}
+		target, ok := s.guard(ctx, state.Transitions[event])
+		if !ok {
+			if target, ok = state.Events[event]; !ok {
+				if len(state.Transitions[event]) > 0 && guardErr == nil {
+					guardErr = &GuardError{State: path[i], Event: event, Guards: guardNames(state.Transitions[event])}
+				}
+				continue
 			}
-			return next, nil, errors.New("fsm: invalid state")
 		}
+		next := s.enter(target)
+		if state, ok = s.states[next]; ok && state.Action != nil {
+			return next, target, &state, nil
+		}
+		return next, target, nil, ErrInvalidState
+	}
+	if guardErr != nil {
+		return "", "", nil, guardErr
+	}
+	return "", "", nil, ErrRejectedEvent
+}
+
+// enter resolves the leaf entered by entering a state, through Initial or History children.
+func (s *Machine) enter(state StateType) StateType {
+	for range s.states {
+		child := s.states[state].Initial
+		if last, ok := s.history[state]; ok && s.states[state].History {
+			child = last
+		}
+		if child == "" {
+			break
+		}
+		state = child
 	}
-	return "", nil, errors.New("fsm: rejected event")
+	return state
 }
 
-// SendEvent sends an event to the state machine.
+// path lists the ancestors of a state, from the outermost one down to the state itself.
+func (s *Machine) path(state StateType) []StateType {
+	var path []StateType
+	for ; state != "" && len(path) <= len(s.states); state = s.states[state].Parent {
+		path = append([]StateType{state}, path...)
+	}
+	return path
+}
+
+// guard returns the target of the first passing transition.
+func (s *Machine) guard(ctx context.Context, transitions []Transition) (StateType, bool) {
+	for _, t := range transitions {
+		if t.Guard == nil || t.Guard(ctx) {
+			return t.Target, true
+		}
+	}
+	return "", false
+}
+
+// guardNames names each transition, all of them have failed.
+func guardNames(transitions []Transition) []string {
+	names := make([]string, len(transitions))
+	for i, t := range transitions {
+		if names[i] = t.Name; names[i] == "" {
+			names[i] = string(t.Target)
+		}
+	}
+	return names
+}
+
+// SendEvent sends an event to the state machine, the first error of a hook or
+// an ActionRunner stops the chain of events emitted by the actions.
 func (s *Machine) SendEvent(ctx context.Context, event EventType) (err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.send(ctx, event)
+}
+
+// send processes an event and the chain of events emitted by the actions, s.mu is held.
+func (s *Machine) send(ctx context.Context, event EventType) error {
+	s.mailbox.setBusy(true)
+	defer s.mailbox.setBusy(false)
+
 	for {
 		// Determine the next state for the event given the machine's current state.
-		next, state, err := s.nextState(event)
+		next, target, state, err := s.nextState(ctx, event)
 		if err != nil {
 			return err
 		}
 
+		// States are exited from the current leaf and entered down to the next one,
+		// up to their common ancestor, the target itself is always exited and entered again.
+		exits, enters := s.path(s.curr), s.path(next)
+		common := 0
+		for common < len(exits) && common < len(enters) && exits[common] == enters[common] && enters[common] != target {
+			common++
+		}
+		exits, enters = exits[common:], enters[common:]
+
+		// Hooks run before leaving the current state can still cancel the transition.
+		if s.BeforeTransition != nil {
+			if err := s.BeforeTransition(ctx, event, s.curr, next); err != nil {
+				return err
+			}
+		}
+		for i := len(exits) - 1; i >= 0; i-- {
+			if exit := s.states[exits[i]].OnExit; exit != nil {
+				if err := exit(ctx); err != nil {
+					return err
+				}
+			}
+		}
+
 		// Transition over to the next state when event is valid.
 		if s.OnTransition != nil {
 			s.OnTransition(s.curr, next)
 		}
-		s.prev, s.curr = s.curr, next
+		s.prev = s.curr
+		s.disarm(exits...)
+		s.activate(next)
+		s.arm(s.clock.Now(), nil, enters...)
+		for _, enter := range enters {
+			if enter := s.states[enter].OnEnter; enter != nil {
+				if err := enter(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if s.AfterTransition != nil {
+			if err := s.AfterTransition(ctx, event, s.prev, s.curr); err != nil {
+				return err
+			}
+		}
 
 		// Execute the next state's action and loop over again if the event returned is not a no-op.
-		if nextEvent := state.Action.Execute(ctx); nextEvent != "" {
+		nextEvent, err := execute(ctx, state.Action)
+		if err != nil {
+			return err
+		}
+		if nextEvent != "" {
 			event = nextEvent
 			continue
 		}
@@ -83,5 +307,103 @@ func (s *Machine) SendEvent(ctx context.Context, event EventType) (err error) {
 	}
 }
 
-// GetStates tuple of previous and current state
-func (s *Machine) GetStates() (prev, curr StateType) { return s.prev, s.curr }
+// execute runs an ActionRunner or executes an Action.
+func execute(ctx context.Context, action Action) (EventType, error) {
+	if runner, ok := action.(ActionRunner); ok {
+		return runner.Run(ctx)
+	}
+	return action.Execute(ctx), nil
+}
+
+// GetStates tuple of previous and current state, these are leaf states, see GetPaths for their ancestors.
+// As the other methods locking the machine, it should not be called from an action or a hook.
+func (s *Machine) GetStates() (prev, curr StateType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.prev, s.curr
+}
+
+// GetPaths tuple of previous and current active path, from the outermost state down to the leaf state.
+func (s *Machine) GetPaths() (prev, curr []StateType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.path(s.prev), s.path(s.curr)
+}
+
+// Snapshot is the JSON-marshalable state of a Machine, Version is the one of the last
+// Snapshot saved to or restored from a Store.
+type Snapshot struct {
+	Version int64                   `json:"version"`
+	Prev    StateType               `json:"prev,omitempty"`
+	Curr    StateType               `json:"curr"`
+	History map[StateType]StateType `json:"history,omitempty"`
+
+	// EnteredAt holds when each active state was entered, their timeouts are scheduled from it on Restore.
+	EnteredAt map[StateType]time.Time `json:"entered_at,omitempty"`
+}
+
+// Snapshot returns the state of the machine.
+func (s *Machine) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make(map[StateType]StateType, len(s.history))
+	for parent, child := range s.history {
+		history[parent] = child
+	}
+	entered := make(map[StateType]time.Time, len(s.entered))
+	for state, at := range s.entered {
+		entered[state] = at
+	}
+	return Snapshot{Version: s.version, Prev: s.prev, Curr: s.curr, History: history, EnteredAt: entered}
+}
+
+// Restore sets the state of the machine without calling any hook or action, the timeouts of the
+// active states are scheduled again for the time left since EnteredAt, now when missing,
+// return ErrInvalidState when the current state of the snapshot is undefined or has no Action.
+func (s *Machine) Restore(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states[snapshot.Curr].Action == nil {
+		return ErrInvalidState
+	}
+	s.history = make(map[StateType]StateType, len(snapshot.History))
+	for parent, child := range snapshot.History {
+		s.history[parent] = child
+	}
+	s.version, s.prev = snapshot.Version, snapshot.Prev
+	s.disarm(s.path(s.curr)...)
+	s.activate(snapshot.Curr)
+	s.arm(s.clock.Now(), snapshot.EnteredAt, s.path(s.curr)...)
+	return nil
+}
+
+// Load restores the machine from the snapshot of id, the machine is left untouched
+// when the store has none, so it starts from its initial state.
+func (s *Machine) Load(ctx context.Context, store Store, id string) error {
+	snapshot, err := store.Load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Restore(snapshot)
+}
+
+// Save saves the snapshot of the machine to id with the next version, ErrConflict is returned
+// when another machine has saved id since this one was loaded, it should then be loaded again.
+func (s *Machine) Save(ctx context.Context, store Store, id string) error {
+	snapshot := s.Snapshot()
+	snapshot.Version++
+	if err := store.Save(ctx, id, snapshot); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.version = snapshot.Version
+	s.mu.Unlock()
+	return nil
+}
@@ -2,6 +2,8 @@ package fsm_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
 	"os"
 	"testing"
@@ -19,17 +21,17 @@ func Test_pkg_fsm(t *testing.T) {
 	Expect := NewWithT(t).Expect
 
 	m := fsm.New(StateOff, fsm.States{
-		StateOff: fsm.State{&OffAction{}, fsm.Events{
+		StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{
 			EventBroken: StateBroken,
 			EventRandom: StateRandom,
 			EventOn:     StateOn,
 		}},
-		StateOn: fsm.State{&OnAction{}, fsm.Events{
+		StateOn: fsm.State{Action: &OnAction{}, Events: fsm.Events{
 			EventBroken: StateBroken,
 			EventRandom: StateRandom,
 			EventOff:    StateOff,
 		}},
-		StateRandom: fsm.State{&RandomAction{}, fsm.Events{
+		StateRandom: fsm.State{Action: &RandomAction{}, Events: fsm.Events{
 			EventBroken: StateBroken,
 			EventOn:     StateOn,
 			EventOff:    StateOff,
@@ -51,6 +53,237 @@ func Test_pkg_fsm(t *testing.T) {
 	Expect(m.SendEvent(ctx, EventBroken)).To(HaveOccurred())
 }
 
+func Test_pkg_fsm_guard(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	type amountKey struct{}
+	under := func(limit int) fsm.GuardFunc {
+		return func(ctx context.Context) bool { return ctx.Value(amountKey{}).(int) < limit }
+	}
+	newMachine := func(fallback fsm.Events) *fsm.Machine {
+		return fsm.New(StatePending, fsm.States{
+			StatePending: fsm.State{Action: &OffAction{}, Events: fallback, Transitions: fsm.Transitions{
+				EventApprove: {
+					{Name: "under 100", Guard: under(100), Target: StateApproved},
+					{Guard: under(1000), Target: StateReview},
+				},
+			}},
+			StateApproved: fsm.State{Action: &OffAction{}},
+			StateReview:   fsm.State{Action: &OffAction{}},
+			StateRejected: fsm.State{Action: &OffAction{}},
+		})
+	}
+	with := func(amount int) context.Context { return context.WithValue(context.Background(), amountKey{}, amount) }
+
+	for amount, state := range map[int]fsm.StateType{50: StateApproved, 500: StateReview} {
+		m := newMachine(nil)
+		Expect(m.SendEvent(with(amount), EventApprove)).To(Succeed())
+		_, curr := m.GetStates()
+		Expect(curr).To(Equal(state))
+	}
+
+	m := newMachine(nil)
+	err := m.SendEvent(with(5000), EventApprove)
+	var guardErr *fsm.GuardError
+	Expect(errors.As(err, &guardErr)).To(BeTrue())
+	Expect(guardErr).To(Equal(&fsm.GuardError{State: StatePending, Event: EventApprove, Guards: []string{"under 100", "Review"}}))
+	Expect(err).To(MatchError(`fsm: event "Approve" rejected in state "Pending" by guards: under 100, Review`))
+	Expect(errors.Is(err, fsm.ErrRejectedEvent)).To(BeTrue())
+	Expect(m.SendEvent(with(0), EventOff)).To(Equal(fsm.ErrRejectedEvent))
+	_, curr := m.GetStates()
+	Expect(curr).To(Equal(StatePending))
+
+	// Events is the fallback when no guard passes
+	m = newMachine(fsm.Events{EventApprove: StateRejected})
+	Expect(m.SendEvent(with(5000), EventApprove)).To(Succeed())
+	_, curr = m.GetStates()
+	Expect(curr).To(Equal(StateRejected))
+
+	Expect(fsm.New("Unknown", nil).SendEvent(with(0), EventApprove)).To(Equal(fsm.ErrRejectedEvent))
+	Expect(fsm.New(StatePending, fsm.States{StatePending: fsm.State{Transitions: fsm.Transitions{
+		EventApprove: {{Target: "Unknown"}},
+	}}}).SendEvent(with(0), EventApprove)).To(Equal(fsm.ErrInvalidState))
+}
+
+func Test_pkg_fsm_hook(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	errHook := errors.New("hook")
+	var calls []string
+	hook := func(name string, err *error) fsm.Hook {
+		return func(context.Context) error { calls = append(calls, name); return *err }
+	}
+	transition := func(name string, err *error) fsm.TransitionHook {
+		return func(_ context.Context, event fsm.EventType, from, to fsm.StateType) error {
+			calls = append(calls, fmt.Sprintf("%s %s %s->%s", name, event, from, to))
+			return *err
+		}
+	}
+	var exitErr, enterErr, beforeErr, afterErr, actionErr error
+	newMachine := func() *fsm.Machine {
+		calls = nil
+		m := fsm.New(StateOff, fsm.States{
+			StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventOn: StateOn}, OnExit: hook("exit Off", &exitErr)},
+			StateOn: fsm.State{
+				Action: fsm.ActionFunc(func(context.Context) (fsm.EventType, error) {
+					calls = append(calls, "action On")
+					return EventBroken, actionErr
+				}),
+				Events:  fsm.Events{EventBroken: StateBroken},
+				OnEnter: hook("enter On", &enterErr),
+			},
+			StateBroken: fsm.State{Action: &OffAction{}},
+		})
+		m.BeforeTransition, m.AfterTransition = transition("before", &beforeErr), transition("after", &afterErr)
+		return m
+	}
+	states := func(m *fsm.Machine) []fsm.StateType {
+		prev, curr := m.GetStates()
+		return []fsm.StateType{prev, curr}
+	}
+
+	m := newMachine()
+	Expect(m.SendEvent(ctx, EventOn)).To(Succeed())
+	Expect(calls).To(Equal([]string{
+		"before SwitchToOn Off->On", "exit Off", "enter On", "after SwitchToOn Off->On", "action On",
+		"before SwitchBroken On->Broken", "after SwitchBroken On->Broken",
+	}))
+	Expect(states(m)).To(Equal([]fsm.StateType{StateOn, StateBroken}))
+
+	// errors before leaving the state cancel the transition
+	for _, err := range []*error{&beforeErr, &exitErr} {
+		*err = errHook
+		m = newMachine()
+		Expect(m.SendEvent(ctx, EventOn)).To(Equal(errHook))
+		Expect(states(m)).To(Equal([]fsm.StateType{"", StateOff}))
+		*err = nil
+	}
+	Expect(calls).To(Equal([]string{"before SwitchToOn Off->On", "exit Off"}))
+
+	// errors after entering the state stop the chain of events
+	for _, err := range []*error{&enterErr, &afterErr, &actionErr} {
+		*err = errHook
+		m = newMachine()
+		Expect(m.SendEvent(ctx, EventOn)).To(Equal(errHook))
+		Expect(states(m)).To(Equal([]fsm.StateType{StateOff, StateOn}))
+		*err = nil
+	}
+	Expect(calls).To(Equal([]string{"before SwitchToOn Off->On", "exit Off", "enter On", "after SwitchToOn Off->On", "action On"}))
+
+	Expect(fsm.ActionFunc(func(context.Context) (fsm.EventType, error) { return EventOff, errHook }).Execute(ctx)).To(Equal(EventOff))
+}
+
+func Test_pkg_fsm_hierarchy(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	var calls []string
+	hook := func(name string) fsm.Hook {
+		return func(context.Context) error { calls = append(calls, name); return nil }
+	}
+	newMachine := func(history bool) *fsm.Machine {
+		calls = nil
+		return fsm.New(StateOrdered, fsm.States{
+			StateOrdered: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventShip: StateShipping}},
+			StateShipping: fsm.State{
+				Initial: StatePacked, History: history,
+				Events:  fsm.Events{EventCancel: StateCancelled, EventHold: StateOnHold},
+				OnEnter: hook("enter shipping"), OnExit: hook("exit shipping"),
+			},
+			StatePacked: fsm.State{
+				Action: &OffAction{}, Parent: StateShipping,
+				Events:  fsm.Events{EventDispatch: StateInTransit},
+				OnEnter: hook("enter packed"), OnExit: hook("exit packed"),
+			},
+			StateInTransit: fsm.State{
+				Action: &OffAction{}, Parent: StateShipping,
+				Transitions: fsm.Transitions{EventCancel: {{Guard: func(context.Context) bool { return false }, Target: StateCancelled}}},
+				Events:      fsm.Events{EventDispatch: StateShipping},
+				OnEnter:     hook("enter in_transit"), OnExit: hook("exit in_transit"),
+			},
+			StateOnHold:    fsm.State{Action: &OffAction{}, Events: fsm.Events{EventResume: StateShipping}},
+			StateCancelled: fsm.State{Action: &OffAction{}},
+		})
+	}
+	paths := func(m *fsm.Machine) [][]fsm.StateType {
+		prev, curr := m.GetPaths()
+		return [][]fsm.StateType{prev, curr}
+	}
+
+	m := newMachine(true)
+	Expect(m.SendEvent(ctx, EventShip)).To(Succeed())
+	Expect(paths(m)).To(Equal([][]fsm.StateType{{StateOrdered}, {StateShipping, StatePacked}}))
+	Expect(m.SendEvent(ctx, EventDispatch)).To(Succeed())
+	Expect(paths(m)).To(Equal([][]fsm.StateType{{StateShipping, StatePacked}, {StateShipping, StateInTransit}}))
+	Expect(calls).To(Equal([]string{"enter shipping", "enter packed", "exit packed", "enter in_transit"}))
+
+	// a transition targeting the ancestor exits and enters it again
+	calls = nil
+	Expect(m.SendEvent(ctx, EventDispatch)).To(Succeed())
+	Expect(calls).To(Equal([]string{"exit in_transit", "exit shipping", "enter shipping", "enter in_transit"}))
+
+	// events bubble up, history restores the last active child
+	Expect(m.SendEvent(ctx, EventHold)).To(Succeed())
+	Expect(paths(m)).To(Equal([][]fsm.StateType{{StateShipping, StateInTransit}, {StateOnHold}}))
+	Expect(m.SendEvent(ctx, EventResume)).To(Succeed())
+	_, curr := m.GetStates()
+	Expect(curr).To(Equal(StateInTransit))
+	Expect(m.SendEvent(ctx, EventCancel)).To(Succeed())
+	Expect(paths(m)).To(Equal([][]fsm.StateType{{StateShipping, StateInTransit}, {StateCancelled}}))
+
+	// without history the initial child is entered
+	m = fsm.New(StateShipping, nil)
+	Expect(paths(m)).To(Equal([][]fsm.StateType{nil, {StateShipping}}))
+	m = newMachine(false)
+	for _, event := range []fsm.EventType{EventShip, EventDispatch, EventHold, EventResume} {
+		Expect(m.SendEvent(ctx, event)).To(Succeed())
+	}
+	_, curr = m.GetStates()
+	Expect(curr).To(Equal(StatePacked))
+
+	// a composite initial state is entered down to its leaf, which needs an Action
+	m = fsm.New(StateShipping, fsm.States{
+		StateShipping:  fsm.State{Initial: StatePacked, Events: fsm.Events{EventCancel: StateCancelled}},
+		StatePacked:    fsm.State{Parent: StateShipping, Transitions: fsm.Transitions{EventCancel: {{Name: "never", Guard: func(context.Context) bool { return false }}}}},
+		StateCancelled: fsm.State{Initial: StateOnHold},
+		StateOnHold:    fsm.State{Parent: StateCancelled},
+	})
+	_, curr = m.GetStates()
+	Expect(curr).To(Equal(StatePacked))
+	Expect(m.SendEvent(ctx, EventCancel)).To(Equal(fsm.ErrInvalidState))
+	Expect(m.SendEvent(ctx, EventOn)).To(Equal(fsm.ErrRejectedEvent))
+	m = fsm.New(StatePacked, fsm.States{
+		StatePacked: fsm.State{Transitions: fsm.Transitions{EventCancel: {{Name: "never", Guard: func(context.Context) bool { return false }}}}},
+	})
+	Expect(m.SendEvent(ctx, EventCancel)).To(MatchError(`fsm: event "Cancel" rejected in state "Packed" by guards: never`))
+}
+
+const (
+	StateOrdered   = fsm.StateType("Ordered")
+	StateShipping  = fsm.StateType("Shipping")
+	StatePacked    = fsm.StateType("Packed")
+	StateInTransit = fsm.StateType("InTransit")
+	StateOnHold    = fsm.StateType("OnHold")
+	StateCancelled = fsm.StateType("Cancelled")
+	EventShip      = fsm.EventType("Ship")
+	EventDispatch  = fsm.EventType("Dispatch")
+	EventHold      = fsm.EventType("Hold")
+	EventResume    = fsm.EventType("Resume")
+	EventCancel    = fsm.EventType("Cancel")
+)
+
+const (
+	StatePending  = fsm.StateType("Pending")
+	StateApproved = fsm.StateType("Approved")
+	StateReview   = fsm.StateType("Review")
+	StateRejected = fsm.StateType("Rejected")
+	EventApprove  = fsm.EventType("Approve")
+)
+
 const (
 	StateOff    = fsm.StateType("Off")
 	StateOn     = fsm.StateType("On")
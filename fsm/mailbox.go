@@ -0,0 +1,178 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by a Future when the mailbox is full and its Backpressure rejects the event.
+	ErrQueueFull = errors.New("fsm: queue full")
+	// ErrDropped is returned by a Future whose event is discarded by DropOldest to make room for a newer one.
+	ErrDropped = errors.New("fsm: event dropped")
+	// ErrStopped is returned by a Future when the mailbox is not started or stopped before the event is processed.
+	ErrStopped = errors.New("fsm: mailbox stopped")
+)
+
+// Backpressure is the policy of Post when the mailbox is full.
+type Backpressure int
+
+const (
+	// Block waits for room, until the context of Post is done or the mailbox stops, an event
+	// posted while the machine processes an event is rejected instead, like DropNewest, since
+	// it may come from an action or a hook of the machine which would never get any.
+	Block Backpressure = iota
+	// DropNewest rejects the posted event with ErrQueueFull.
+	DropNewest
+	// DropOldest discards the oldest queued event with ErrDropped to make room for the posted one.
+	DropOldest
+)
+
+// Future is the result of an event posted to the mailbox.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future { return &Future{done: make(chan struct{})} }
+
+func (f *Future) resolve(err error) *Future { f.err = err; close(f.done); return f }
+
+// Done is closed once the event is processed or rejected.
+func (f *Future) Done() <-chan struct{} { return f.done }
+
+// Err returns the error of SendEvent for the event, or why it was not processed, once Done is closed.
+func (f *Future) Err() error { <-f.done; return f.err }
+
+// Wait waits for the result of the event until ctx is done, an action must not wait for an event
+// posted to its own machine, which is processed after it returns.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mailbox is the bounded queue of the queued mode, processed by a single dispatcher.
+type mailbox struct {
+	mu      sync.Mutex
+	queue   []posted
+	size    int
+	policy  Backpressure
+	running bool
+	busy    bool          // busy is set while the machine processes an event, see setBusy.
+	notify  chan struct{} // notify wakes the dispatcher up on Post.
+	room    chan struct{} // room is closed, then replaced, when an event leaves the queue.
+	stopped chan struct{}
+}
+
+type posted struct {
+	ctx    context.Context
+	event  EventType
+	future *Future
+}
+
+// Start runs the dispatcher of the queued mode until ctx is done, the returned channel is closed
+// once it has stopped, events still queued then fail with ErrStopped. The mailbox holds up to size
+// events, at least 1, and applies policy when full. Start does nothing while it is already running.
+func (s *Machine) Start(ctx context.Context, size int, policy Backpressure) <-chan struct{} {
+	q := &s.mailbox
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running {
+		return q.stopped
+	}
+	if size < 1 {
+		size = 1
+	}
+	q.queue, q.size, q.policy, q.running = nil, size, policy, true
+	q.notify, q.room, q.stopped = make(chan struct{}, 1), make(chan struct{}), make(chan struct{})
+	go s.dispatch(ctx)
+	return q.stopped
+}
+
+// Post enqueues an event without waiting for the machine, it is processed in order by the
+// dispatcher with SendEvent and ctx, the returned Future holds its result. Actions may Post
+// to their own machine, unlike SendEvent which would deadlock.
+func (s *Machine) Post(ctx context.Context, event EventType) *Future {
+	q := &s.mailbox
+	future := newFuture()
+	q.mu.Lock()
+	for {
+		if !q.running {
+			q.mu.Unlock()
+			return future.resolve(ErrStopped)
+		}
+		if len(q.queue) < q.size {
+			break
+		}
+		switch {
+		case q.policy == DropOldest:
+			q.queue[0].future.resolve(ErrDropped)
+			q.queue = q.queue[1:]
+			continue
+		case q.policy == DropNewest, q.busy:
+			q.mu.Unlock()
+			return future.resolve(ErrQueueFull)
+		}
+		room, stopped := q.room, q.stopped
+		q.mu.Unlock()
+		select {
+		case <-room:
+		case <-stopped:
+		case <-ctx.Done():
+			return future.resolve(ctx.Err())
+		}
+		q.mu.Lock()
+	}
+	q.queue = append(q.queue, posted{ctx: ctx, event: event, future: future})
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	q.mu.Unlock()
+	return future
+}
+
+// dispatch processes the queued events one at a time until ctx is done.
+func (s *Machine) dispatch(ctx context.Context) {
+	q := &s.mailbox
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 || ctx.Err() != nil {
+			q.mu.Unlock()
+			select {
+			case <-q.notify:
+				continue
+			case <-ctx.Done():
+			}
+			q.mu.Lock()
+			for _, p := range q.queue {
+				p.future.resolve(ErrStopped)
+			}
+			q.queue, q.running = nil, false
+			close(q.stopped)
+			q.mu.Unlock()
+			return
+		}
+		p := q.queue[0]
+		q.queue = q.queue[1:]
+		close(q.room)
+		q.room = make(chan struct{})
+		q.mu.Unlock()
+
+		p.future.resolve(s.SendEvent(p.ctx, p.event))
+	}
+}
+
+// setBusy marks whether the machine processes an event, s.mu is held, whichever of SendEvent,
+// the dispatcher or a timeout sends it.
+func (q *mailbox) setBusy(busy bool) {
+	q.mu.Lock()
+	q.busy = busy
+	q.mu.Unlock()
+}
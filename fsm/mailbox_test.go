@@ -0,0 +1,205 @@
+package fsm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo/fsm"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_fsm_mailbox(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	// newMachine switches On with action, and Off again
+	newMachine := func(action fsm.ActionFunc) *fsm.Machine {
+		return fsm.New(StateOff, fsm.States{
+			StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventOn: StateOn}},
+			StateOn:  fsm.State{Action: action, Events: fsm.Events{EventOff: StateOff}},
+		})
+	}
+	// blocking holds the dispatcher in the action of On until release is closed
+	blocking := func() (*fsm.Machine, chan struct{}, chan struct{}) {
+		entered, release := make(chan struct{}, 1), make(chan struct{})
+		return newMachine(func(context.Context) (fsm.EventType, error) {
+			entered <- struct{}{}
+			<-release
+			return "", nil
+		}), entered, release
+	}
+	// hold keeps the machine locked outside of any event, in SetClock, until release is called
+	hold := func(m *fsm.Machine) (release func()) {
+		clock := &heldClock{held: make(chan struct{}), release: make(chan struct{})}
+		go m.SetClock(clock)
+		<-clock.held
+		return func() { close(clock.release) }
+	}
+
+	t.Run("Post", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// actions post to their own machine, a full mailbox rejects them instead of blocking
+		var off, full *fsm.Future
+		var m *fsm.Machine
+		m = newMachine(func(ctx context.Context) (fsm.EventType, error) {
+			off, full = m.Post(ctx, EventOff), m.Post(ctx, EventOff)
+			return "", nil
+		})
+		Expect(m.Post(ctx, EventOn).Err()).To(Equal(fsm.ErrStopped))
+		stopped := m.Start(ctx, 0, fsm.Block)
+		Expect(m.Start(ctx, 1, fsm.DropNewest)).To(Equal(stopped))
+
+		Expect(m.Post(ctx, EventOn).Wait(ctx)).To(Succeed())
+		Expect(full.Err()).To(Equal(fsm.ErrQueueFull))
+		<-off.Done()
+		Expect(off.Err()).To(Succeed())
+		_, curr := m.GetStates()
+		Expect(curr).To(Equal(StateOff))
+		Expect(m.Post(ctx, EventOff).Err()).To(Equal(fsm.ErrRejectedEvent))
+
+		cancel()
+		<-stopped
+		Expect(m.Post(ctx, EventOn).Err()).To(Equal(fsm.ErrStopped))
+	})
+	t.Run("Post outside of the dispatcher", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// an action run by SendEvent or by a timeout fills the mailbox, the dispatcher takes at
+		// most one event before waiting for the machine, the last Post is rejected instead of
+		// blocking, whatever its context
+		var m *fsm.Machine
+		var futures []*fsm.Future
+		post := func(context.Context) (fsm.EventType, error) {
+			for i := 0; i < 3; i++ {
+				futures = append(futures, m.Post(context.Background(), "Unknown"))
+			}
+			return "", nil
+		}
+		m = newMachine(post)
+		m.Start(ctx, 1, fsm.Block)
+		Expect(m.SendEvent(ctx, EventOn)).To(Succeed())
+		Expect(futures[2].Err()).To(Equal(fsm.ErrQueueFull))
+
+		clock := fsm.NewManualClock(time.Now())
+		m = fsm.New(StateOn, fsm.States{
+			StateOn:  fsm.State{Action: &OffAction{}, Timeouts: []fsm.Timeout{{After: time.Second, Event: EventOff}}, Events: fsm.Events{EventOff: StateOff}},
+			StateOff: fsm.State{Action: fsm.ActionFunc(post)},
+		})
+		m.SetClock(clock)
+		m.OnTimeout = func(event fsm.EventType, err error) { Expect(err).NotTo(HaveOccurred()) }
+		m.Start(ctx, 1, fsm.Block)
+		futures = nil
+		clock.Advance(time.Second)
+		Expect(futures[2].Err()).To(Equal(fsm.ErrQueueFull))
+		_, curr := m.GetStates()
+		Expect(curr).To(Equal(StateOff))
+	})
+	t.Run("DropNewest", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m, entered, release := blocking()
+		m.Start(ctx, 1, fsm.DropNewest)
+
+		on := m.Post(ctx, EventOn)
+		<-entered
+		off := m.Post(ctx, EventOff)
+		Expect(m.Post(ctx, EventOff).Err()).To(Equal(fsm.ErrQueueFull))
+		close(release)
+		Expect(on.Err()).To(Succeed())
+		Expect(off.Err()).To(Succeed())
+	})
+	t.Run("DropOldest", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m, entered, release := blocking()
+		m.Start(ctx, 1, fsm.DropOldest)
+
+		on := m.Post(ctx, EventOn)
+		<-entered
+		dropped, off := m.Post(ctx, "Unknown"), m.Post(ctx, EventOff)
+		Expect(dropped.Err()).To(Equal(fsm.ErrDropped))
+		close(release)
+		Expect(on.Err()).To(Succeed())
+		Expect(off.Err()).To(Succeed())
+	})
+	t.Run("Block", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m, entered, release := blocking()
+		m.Start(ctx, 1, fsm.Block)
+
+		// the machine processes an event, a Post may come from its action
+		on := m.Post(ctx, EventOn)
+		<-entered
+		off := m.Post(ctx, EventOff)
+		Expect(m.Post(ctx, EventOn).Err()).To(Equal(fsm.ErrQueueFull))
+		close(release)
+		Expect(on.Err()).To(Succeed())
+		Expect(off.Err()).To(Succeed())
+
+		// the dispatcher waits for the machine held outside of any event, a Post waits for room
+		unhold := hold(m)
+		on = m.Post(ctx, EventOn)
+		time.Sleep(10 * time.Millisecond)
+		off = m.Post(ctx, EventOff)
+		timeout, cancelTimeout := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancelTimeout()
+		Expect(m.Post(timeout, EventOn).Err()).To(Equal(context.DeadlineExceeded))
+		Expect(off.Wait(timeout)).To(Equal(context.DeadlineExceeded))
+
+		// a blocked Post gets the room left by the next event processed
+		blocked := make(chan *fsm.Future)
+		go func() { blocked <- m.Post(ctx, EventOn) }()
+		time.Sleep(10 * time.Millisecond)
+		unhold()
+		Expect(on.Err()).To(Succeed())
+		Expect(off.Err()).To(Succeed())
+		<-entered
+		Expect((<-blocked).Err()).To(Succeed())
+	})
+	t.Run("Stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		m, _, release := blocking()
+		close(release)
+		stopped := m.Start(ctx, 1, fsm.Block)
+
+		unhold := hold(m)
+		on := m.Post(ctx, EventOn)
+		time.Sleep(10 * time.Millisecond)
+		queued := m.Post(ctx, EventOff)
+		blocked := make(chan *fsm.Future)
+		go func() { blocked <- m.Post(context.Background(), EventOff) }()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		unhold()
+		<-stopped
+		Expect(on.Err()).To(Succeed())
+		Expect(queued.Err()).To(Equal(fsm.ErrStopped))
+		Expect((<-blocked).Err()).To(Equal(fsm.ErrStopped))
+
+		// the mailbox can be started again
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		Expect(m.Start(ctx, 1, fsm.Block)).NotTo(Equal(stopped))
+		Expect(m.Post(ctx, EventOff).Err()).To(Succeed())
+	})
+}
+
+// heldClock holds the machine in SetClock, on its first Now, until release is closed.
+type heldClock struct {
+	once          sync.Once
+	held, release chan struct{}
+}
+
+func (c *heldClock) Now() time.Time {
+	c.once.Do(func() { close(c.held) })
+	<-c.release
+	return time.Now()
+}
+
+func (*heldClock) AfterFunc(time.Duration, func()) fsm.Timer { return nil }
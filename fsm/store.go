@@ -0,0 +1,136 @@
+package fsm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hokonco/kitgo"
+)
+
+var (
+	// ErrNotFound is returned by Store.Load when no snapshot is stored for an id.
+	ErrNotFound = errors.New("fsm: snapshot not found")
+	// ErrConflict is returned by Store.Save when the stored version is not the one preceding the snapshot.
+	ErrConflict = errors.New("fsm: version conflict")
+)
+
+// Store persists the snapshots of machines by id, see Machine.Load and Machine.Save.
+type Store interface {
+	// Load returns the last snapshot saved for id, or ErrNotFound.
+	Load(ctx context.Context, id string) (Snapshot, error)
+	// Save stores the snapshot for id only if the stored version is snapshot.Version-1,
+	// version 0 meaning none is stored yet, ErrConflict is returned otherwise.
+	Save(ctx context.Context, id string, snapshot Snapshot) error
+}
+
+// SQLStore is a Store backed by a table with an unique id, an integer version and
+// a text snapshot column, queries use the "?" placeholder.
+type SQLStore struct {
+	db    *kitgo.SQLWrapper
+	table string
+}
+
+// NewSQLStore create new SQLStore on table.
+func NewSQLStore(db *kitgo.SQLWrapper, table string) *SQLStore {
+	return &SQLStore{db: db, table: table}
+}
+
+// Load implement Store
+func (x *SQLStore) Load(ctx context.Context, id string) (Snapshot, error) {
+	var _ Store = x
+	var b []byte
+	err := x.db.QueryRowContext(ctx, fmt.Sprintf("SELECT snapshot FROM %s WHERE id = ?", x.table), id).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snapshot{}, ErrNotFound
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("fsm: sql store: %w", err)
+	}
+	return decodeSnapshot(b)
+}
+
+// Save implement Store, the row is inserted for the first version and updated
+// on the previous version for the next ones.
+func (x *SQLStore) Save(ctx context.Context, id string, snapshot Snapshot) error {
+	b, _ := json.Marshal(snapshot)
+	q, args := fmt.Sprintf("UPDATE %s SET version = ?, snapshot = ? WHERE id = ? AND version = ?", x.table),
+		[]interface{}{snapshot.Version, string(b), id, snapshot.Version - 1}
+	if snapshot.Version == 1 {
+		q, args = fmt.Sprintf("INSERT INTO %[1]s (id, version, snapshot) SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE id = ?)", x.table),
+			[]interface{}{id, snapshot.Version, string(b), id}
+	}
+	res, err := x.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("fsm: sql store: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fsm: sql store: %w", err)
+	}
+	if n < 1 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// RedisStore is a Store backed by a hash per id holding the version and the snapshot.
+type RedisStore struct {
+	client *kitgo.RedisWrapper
+	prefix string
+}
+
+// NewRedisStore create new RedisStore, prefix is prepended to each id, default to "fsm:".
+func NewRedisStore(client *kitgo.RedisWrapper, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "fsm:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Load implement Store
+func (x *RedisStore) Load(ctx context.Context, id string) (Snapshot, error) {
+	var _ Store = x
+	b, err := x.client.HGet(ctx, x.prefix+id, "snapshot").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Snapshot{}, ErrNotFound
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("fsm: redis store: %w", err)
+	}
+	return decodeSnapshot(b)
+}
+
+// Save implement Store, the version is compared and set by a script.
+func (x *RedisStore) Save(ctx context.Context, id string, snapshot Snapshot) error {
+	b, _ := json.Marshal(snapshot)
+	saved, err := redisStoreSave.Run(ctx, x.client, []string{x.prefix + id}, snapshot.Version, string(b)).Int()
+	if err != nil {
+		return fmt.Errorf("fsm: redis store: %w", err)
+	}
+	if saved != 1 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// redisStoreSave sets the version ARGV[1] and the snapshot ARGV[2] when the
+// stored version precedes it, the reply is 1 when saved and 0 otherwise.
+var redisStoreSave = redis.NewScript(`
+local version = tonumber(redis.call('HGET', KEYS[1], 'version')) or 0
+if version + 1 ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'version', ARGV[1], 'snapshot', ARGV[2])
+return 1
+`)
+
+func decodeSnapshot(b []byte) (snapshot Snapshot, err error) {
+	if err = json.Unmarshal(b, &snapshot); err != nil {
+		err = fmt.Errorf("fsm: invalid snapshot: %w", err)
+	}
+	return
+}
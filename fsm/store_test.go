@@ -0,0 +1,159 @@
+package fsm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	"github.com/hokonco/kitgo/fsm"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_fsm_snapshot(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	t0 := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	states := func() fsm.States {
+		return fsm.States{
+			StateOrdered:   fsm.State{Action: &OffAction{}, Events: fsm.Events{EventShip: StateShipping}},
+			StateShipping:  fsm.State{Initial: StatePacked, History: true, Events: fsm.Events{EventHold: StateOnHold}},
+			StatePacked:    fsm.State{Action: &OffAction{}, Parent: StateShipping, Events: fsm.Events{EventDispatch: StateInTransit}},
+			StateInTransit: fsm.State{Action: &OffAction{}, Parent: StateShipping},
+			StateOnHold:    fsm.State{Action: &OffAction{}, Events: fsm.Events{EventResume: StateShipping}},
+		}
+	}
+
+	m := fsm.New(StateOrdered, states())
+	m.SetClock(fsm.NewManualClock(t0))
+	for _, event := range []fsm.EventType{EventShip, EventDispatch, EventHold} {
+		Expect(m.SendEvent(ctx, event)).To(Succeed())
+	}
+	b, err := json.Marshal(m.Snapshot())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(string(b)).To(Equal(`{"version":0,"prev":"InTransit","curr":"OnHold","history":{"Shipping":"InTransit"},"entered_at":{"OnHold":"2021-06-01T12:00:00Z"}}`))
+
+	// the restored machine resumes where the other one stopped, history included
+	var snapshot fsm.Snapshot
+	Expect(json.Unmarshal(b, &snapshot)).To(Succeed())
+	restored := fsm.New(StateOrdered, states())
+	restored.SetClock(fsm.NewManualClock(t0))
+	Expect(restored.Restore(snapshot)).To(Succeed())
+	Expect(restored.SendEvent(ctx, EventResume)).To(Succeed())
+	_, curr := restored.GetPaths()
+	Expect(curr).To(Equal([]fsm.StateType{StateShipping, StateInTransit}))
+
+	Expect(restored.Restore(fsm.Snapshot{Curr: StateShipping})).To(Equal(fsm.ErrInvalidState))
+	Expect(restored.Restore(fsm.Snapshot{Version: 3, Curr: StatePacked})).To(Succeed())
+	Expect(restored.Snapshot()).To(Equal(fsm.Snapshot{Version: 3, Curr: StatePacked,
+		History:   map[fsm.StateType]fsm.StateType{StateShipping: StatePacked},
+		EnteredAt: map[fsm.StateType]time.Time{StateShipping: t0, StatePacked: t0},
+	}))
+}
+
+func Test_pkg_fsm_store(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	t0 := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	errDown := errors.New("down")
+	newMachine := func() *fsm.Machine {
+		m := fsm.New(StateOff, fsm.States{
+			StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventOn: StateOn}},
+			StateOn:  fsm.State{Action: &OnAction{}, Events: fsm.Events{EventOff: StateOff}},
+		})
+		m.SetClock(fsm.NewManualClock(t0))
+		return m
+	}
+
+	t.Run("SQL", func(t *testing.T) {
+		db, mock := kitgo.SQL.Test()
+		defer func() { Expect(mock.ExpectationsWereMet()).To(Succeed()) }()
+		store := fsm.NewSQLStore(db, "machines")
+		selectQ := regexp.QuoteMeta("SELECT snapshot FROM machines WHERE id = ?")
+		insertQ := regexp.QuoteMeta("INSERT INTO machines (id, version, snapshot) SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM machines WHERE id = ?)")
+		updateQ := regexp.QuoteMeta("UPDATE machines SET version = ?, snapshot = ? WHERE id = ? AND version = ?")
+
+		// a new machine is not found and starts from its initial state
+		m := newMachine()
+		mock.ExpectQuery(selectQ).WithArgs("a").WillReturnRows(mock.NewRows("snapshot"))
+		Expect(m.Load(ctx, store, "a")).To(Succeed())
+		Expect(m.SendEvent(ctx, EventOn)).To(Succeed())
+		mock.ExpectExec(insertQ).
+			WithArgs("a", 1, `{"version":1,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`, "a").WillReturnResult(mock.NewResult(0, nil, 1, nil))
+		Expect(m.Save(ctx, store, "a")).To(Succeed())
+		Expect(m.Snapshot().Version).To(Equal(int64(1)))
+
+		// two workers load the same version, only the first one can save
+		mock.ExpectQuery(selectQ).WithArgs("a").WillReturnRows(mock.NewRows("snapshot").AddRow(`{"version":1,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`))
+		mock.ExpectQuery(selectQ).WithArgs("a").WillReturnRows(mock.NewRows("snapshot").AddRow([]byte(`{"version":1,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`)))
+		m1, m2 := newMachine(), newMachine()
+		Expect(m1.Load(ctx, store, "a")).To(Succeed())
+		Expect(m2.Load(ctx, store, "a")).To(Succeed())
+		_, curr := m1.GetStates()
+		Expect(curr).To(Equal(StateOn))
+		Expect(m1.SendEvent(ctx, EventOff)).To(Succeed())
+		Expect(m2.SendEvent(ctx, EventOff)).To(Succeed())
+		mock.ExpectExec(updateQ).
+			WithArgs(2, `{"version":2,"prev":"On","curr":"Off","entered_at":{"Off":"2021-06-01T12:00:00Z"}}`, "a", 1).WillReturnResult(mock.NewResult(0, nil, 1, nil))
+		mock.ExpectExec(updateQ).
+			WithArgs(2, `{"version":2,"prev":"On","curr":"Off","entered_at":{"Off":"2021-06-01T12:00:00Z"}}`, "a", 1).WillReturnResult(mock.NewResult(0, nil, 0, nil))
+		Expect(m1.Save(ctx, store, "a")).To(Succeed())
+		Expect(m2.Save(ctx, store, "a")).To(Equal(fsm.ErrConflict))
+		Expect(m2.Snapshot().Version).To(Equal(int64(1)))
+
+		mock.ExpectExec(updateQ).WillReturnError(errDown)
+		Expect(m2.Save(ctx, store, "a")).To(MatchError("fsm: sql store: down"))
+		mock.ExpectExec(updateQ).WillReturnResult(mock.NewResult(0, nil, 0, errDown))
+		Expect(m2.Save(ctx, store, "a")).To(MatchError("fsm: sql store: down"))
+
+		// a failed query is not mistaken for a missing snapshot
+		mock.ExpectQuery(selectQ).WillReturnError(errDown)
+		err := m.Load(ctx, store, "a")
+		Expect(err).To(MatchError("fsm: sql store: down"))
+		Expect(errors.Is(err, errDown)).To(BeTrue())
+		mock.ExpectQuery(selectQ).WillReturnRows(mock.NewRows("snapshot").AddRow(1))
+		Expect(m.Load(ctx, store, "a")).To(MatchError(HavePrefix("fsm: invalid snapshot: ")))
+		mock.ExpectQuery(selectQ).WillReturnRows(mock.NewRows("snapshot").AddRow(`{"curr":"Unknown"}`))
+		Expect(m.Load(ctx, store, "a")).To(Equal(fsm.ErrInvalidState))
+	})
+	t.Run("Redis", func(t *testing.T) {
+		client, mock := kitgo.Redis.Test()
+		defer func() { Expect(mock.ExpectationsWereMet()).To(Succeed()) }()
+		store := fsm.NewRedisStore(client, "")
+
+		m := newMachine()
+		mock.ExpectHGet("fsm:a", "snapshot").RedisNil()
+		Expect(m.Load(ctx, store, "a")).To(Succeed())
+		Expect(m.SendEvent(ctx, EventOn)).To(Succeed())
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"fsm:a"}, int64(1), `{"version":1,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`).SetVal(int64(1))
+		Expect(m.Save(ctx, store, "a")).To(Succeed())
+
+		mock.ExpectHGet("fsm:a", "snapshot").SetVal(`{"version":1,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`)
+		m = newMachine()
+		Expect(m.Load(ctx, store, "a")).To(Succeed())
+		Expect(m.Snapshot()).To(Equal(fsm.Snapshot{Version: 1, Prev: StateOff, Curr: StateOn,
+			History: map[fsm.StateType]fsm.StateType{}, EnteredAt: map[fsm.StateType]time.Time{StateOn: t0},
+		}))
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"fsm:a"}, int64(2), `{"version":2,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`).SetVal(int64(0))
+		Expect(m.Save(ctx, store, "a")).To(Equal(fsm.ErrConflict))
+
+		// the script is loaded by EVAL when missing from the script cache
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"fsm:a"}, int64(2), `{"version":2,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`).
+			SetErr(errors.New("NOSCRIPT No matching script"))
+		mock.Regexp().ExpectEval("HSET", []string{"fsm:a"}, int64(2), `{"version":2,"prev":"Off","curr":"On","entered_at":{"On":"2021-06-01T12:00:00Z"}}`).SetErr(errDown)
+		Expect(m.Save(ctx, store, "a")).To(MatchError("fsm: redis store: down"))
+
+		mock.ExpectHGet("fsm:a", "snapshot").SetErr(errDown)
+		Expect(m.Load(ctx, store, "a")).To(MatchError("fsm: redis store: down"))
+		mock.ExpectHGet("fsm:a", "snapshot").SetVal(`{`)
+		Expect(m.Load(ctx, store, "a")).To(MatchError(HavePrefix("fsm: invalid snapshot: ")))
+		Expect(fsm.NewRedisStore(client, "wf:")).NotTo(BeNil())
+	})
+}
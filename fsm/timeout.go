@@ -0,0 +1,167 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timeout fires Event once After is spent in a state.
+type Timeout struct {
+	After time.Duration
+	Event EventType
+}
+
+// Clock schedules the timeouts of a Machine, default to the time package, see SetClock.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled by a Clock.
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                            { return time.Now() }
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// SetClock replaces the clock of the machine, the active states are entered again at the time
+// of clock for their timeouts, it should be called right after New: the timers New scheduled
+// with the time package for the initial state are stopped and scheduled again with clock.
+func (s *Machine) SetClock(clock Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(s.curr)
+	s.disarm(path...)
+	s.clock = clock
+	s.arm(clock.Now(), nil, path...)
+}
+
+// arm records when states are entered, at enteredAt or now when missing, and schedules their timeouts
+// for the time left, s.mu is held.
+func (s *Machine) arm(now time.Time, enteredAt map[StateType]time.Time, states ...StateType) {
+	if s.entered == nil {
+		s.entered, s.timers = map[StateType]time.Time{}, map[StateType]*[]Timer{}
+	}
+	for _, state := range states {
+		at, ok := enteredAt[state]
+		if !ok {
+			at = now
+		}
+		s.entered[state] = at
+		timers := &[]Timer{}
+		s.timers[state] = timers
+		for _, t := range s.states[state].Timeouts {
+			state, event := state, t.Event
+			*timers = append(*timers, s.clock.AfterFunc(t.After-now.Sub(at), func() { s.timeout(state, timers, event) }))
+		}
+	}
+}
+
+// disarm cancels the timeouts of states left, s.mu is held.
+func (s *Machine) disarm(states ...StateType) {
+	for _, state := range states {
+		if timers, ok := s.timers[state]; ok {
+			for _, t := range *timers {
+				t.Stop()
+			}
+		}
+		delete(s.timers, state)
+		delete(s.entered, state)
+	}
+}
+
+// timeout sends the event of a timer unless its state was left in the meantime.
+func (s *Machine) timeout(state StateType, timers *[]Timer, event EventType) {
+	s.mu.Lock()
+	if s.timers[state] != timers {
+		s.mu.Unlock()
+		return
+	}
+	err := s.send(context.Background(), event)
+	s.mu.Unlock()
+	if s.OnTimeout != nil {
+		s.OnTimeout(event, err)
+	}
+}
+
+// ManualClock is a Clock for tests, its time only moves on Advance, which runs the timers due
+// in order, the ones of the same time in the order they were scheduled.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	at    time.Time
+	f     func()
+}
+
+// NewManualClock create new ManualClock at now.
+func NewManualClock(now time.Time) *ManualClock { return &ManualClock{now: now} }
+
+// Now implement Clock
+func (c *ManualClock) Now() time.Time {
+	var _ Clock = c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc implement Clock, f is called by Advance.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the time by d, calling each timer due on the way at its time, Advance(0) calls the
+// timers already due, such as the ones restored past their timeout. Timers call SendEvent,
+// Advance must not be called by an action.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		i := -1
+		for j, t := range c.timers {
+			if !t.at.After(end) && (i < 0 || t.at.Before(c.timers[i].at)) {
+				i = j
+			}
+		}
+		if i < 0 {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[i]
+		c.timers = append(c.timers[:i], c.timers[i+1:]...)
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+}
+
+// Stop implement Timer
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.timers {
+		if c.timers[i] == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package fsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo/fsm"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_fsm_timeout(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	t0 := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	var fired []string
+	newMachine := func(clock fsm.Clock) *fsm.Machine {
+		fired = nil
+		m := fsm.New(StateAwaiting, fsm.States{
+			StateAwaiting: fsm.State{
+				Action:   &OffAction{},
+				Events:   fsm.Events{EventPay: StatePaid, EventExpire: StateExpired},
+				Timeouts: []fsm.Timeout{{After: 15 * time.Minute, Event: EventExpire}, {After: 10 * time.Minute, Event: EventRemind}},
+			},
+			StatePaid:    fsm.State{Action: &OffAction{}, Events: fsm.Events{EventShip: StateShipping}},
+			StateExpired: fsm.State{Action: &OffAction{}},
+			StateShipping: fsm.State{
+				Initial:  StatePacked,
+				Events:   fsm.Events{EventExpire: StateExpired},
+				Timeouts: []fsm.Timeout{{After: 48 * time.Hour, Event: EventExpire}},
+			},
+			StatePacked:    fsm.State{Action: &OffAction{}, Parent: StateShipping, Events: fsm.Events{EventDispatch: StateInTransit}},
+			StateInTransit: fsm.State{Action: &OffAction{}, Parent: StateShipping},
+		})
+		m.SetClock(clock)
+		m.OnTimeout = func(event fsm.EventType, err error) { fired = append(fired, fmt.Sprint(event, " ", err)) }
+		return m
+	}
+	current := func(m *fsm.Machine) fsm.StateType { _, curr := m.GetStates(); return curr }
+
+	clock := fsm.NewManualClock(t0)
+	m := newMachine(clock)
+	clock.Advance(14 * time.Minute)
+	Expect(current(m)).To(Equal(StateAwaiting))
+	Expect(fired).To(Equal([]string{"Remind fsm: rejected event"}))
+	clock.Advance(time.Minute)
+	Expect(current(m)).To(Equal(StateExpired))
+	Expect(fired).To(Equal([]string{"Remind fsm: rejected event", "Expire <nil>"}))
+	Expect(clock.Now()).To(Equal(t0.Add(15 * time.Minute)))
+
+	// the timers of the initial state scheduled by New with the time package are replaced
+	m = fsm.New(StateAwaiting, fsm.States{
+		StateAwaiting: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventExpire: StateExpired}, Timeouts: []fsm.Timeout{{After: time.Millisecond, Event: EventExpire}}},
+		StateExpired:  fsm.State{Action: &OffAction{}},
+	})
+	clock = fsm.NewManualClock(t0)
+	m.SetClock(clock)
+	time.Sleep(10 * time.Millisecond)
+	Expect(current(m)).To(Equal(StateAwaiting))
+	clock.Advance(time.Millisecond)
+	Expect(current(m)).To(Equal(StateExpired))
+
+	// timers are cancelled when the state is left
+	clock = fsm.NewManualClock(t0)
+	m = newMachine(clock)
+	clock.Advance(5 * time.Minute)
+	Expect(m.SendEvent(ctx, EventPay)).To(Succeed())
+	clock.Advance(time.Hour)
+	Expect(current(m)).To(Equal(StatePaid))
+	Expect(fired).To(BeNil())
+
+	// a composite state keeps its timers while its children change
+	Expect(m.SendEvent(ctx, EventShip)).To(Succeed())
+	clock.Advance(24 * time.Hour)
+	Expect(m.SendEvent(ctx, EventDispatch)).To(Succeed())
+	clock.Advance(24 * time.Hour)
+	Expect(current(m)).To(Equal(StateExpired))
+	Expect(fired).To(Equal([]string{"Expire <nil>"}))
+
+	// timers survive a snapshot, restored for the time left since the state was entered
+	clock = fsm.NewManualClock(t0)
+	m = newMachine(clock)
+	clock.Advance(12 * time.Minute)
+	snapshot := m.Snapshot()
+	Expect(snapshot.EnteredAt).To(Equal(map[fsm.StateType]time.Time{StateAwaiting: t0}))
+	clock = fsm.NewManualClock(t0.Add(12 * time.Minute))
+	m = newMachine(clock)
+	Expect(m.Restore(snapshot)).To(Succeed())
+	clock.Advance(2 * time.Minute)
+	Expect(current(m)).To(Equal(StateAwaiting))
+	clock.Advance(time.Minute)
+	Expect(current(m)).To(Equal(StateExpired))
+
+	clock = fsm.NewManualClock(t0.Add(time.Hour))
+	m = newMachine(clock)
+	Expect(m.Restore(snapshot)).To(Succeed())
+	clock.Advance(0)
+	Expect(current(m)).To(Equal(StateExpired))
+	Expect(fired).To(Equal([]string{"Remind fsm: rejected event", "Expire <nil>"}))
+
+	// a timer which fires after its state was left is ignored
+	clock = fsm.NewManualClock(t0)
+	m = newMachine(leakyClock{clock})
+	Expect(m.SendEvent(ctx, EventPay)).To(Succeed())
+	clock.Advance(time.Hour)
+	Expect(current(m)).To(Equal(StatePaid))
+	Expect(fired).To(BeNil())
+
+	timer := clock.AfterFunc(time.Second, func() {})
+	Expect(timer.Stop()).To(BeTrue())
+	Expect(timer.Stop()).To(BeFalse())
+
+	// the default clock is the time package
+	done := make(chan string, 1)
+	m = fsm.New(StatePaid, fsm.States{
+		StatePaid:     fsm.State{Action: &OffAction{}, Events: fsm.Events{EventRemind: StateAwaiting}},
+		StateAwaiting: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventExpire: StateExpired}, Timeouts: []fsm.Timeout{{After: time.Millisecond, Event: EventExpire}}},
+		StateExpired:  fsm.State{Action: &OffAction{}},
+	})
+	m.OnTimeout = func(event fsm.EventType, err error) { done <- fmt.Sprint(event, " ", err) }
+	Expect(m.SendEvent(ctx, EventRemind)).To(Succeed())
+	// the state is read while the timer of the time package changes it
+	NewWithT(t).Eventually(func() fsm.StateType { return current(m) }).Should(Equal(StateExpired))
+	Expect(<-done).To(Equal("Expire <nil>"))
+	Expect(m.Snapshot().EnteredAt[StateExpired]).To(BeTemporally("~", time.Now(), time.Second))
+}
+
+// leakyClock never stops its timers, as when a timer fires while its state is being left.
+type leakyClock struct{ *fsm.ManualClock }
+
+func (c leakyClock) AfterFunc(d time.Duration, f func()) fsm.Timer {
+	c.ManualClock.AfterFunc(d, f)
+	return leakyTimer{}
+}
+
+type leakyTimer struct{}
+
+func (leakyTimer) Stop() bool { return false }
+
+const (
+	StateAwaiting = fsm.StateType("AwaitingPayment")
+	StatePaid     = fsm.StateType("Paid")
+	StateExpired  = fsm.StateType("Expired")
+	EventPay      = fsm.EventType("Pay")
+	EventExpire   = fsm.EventType("Expire")
+	EventRemind   = fsm.EventType("Remind")
+)
@@ -0,0 +1,211 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hokonco/kitgo"
+)
+
+// Problems reported by Validate, errors.Is matches them by Code.
+var (
+	ErrUnreachable = &kitgo.Error{Code: "unreachable"}
+	ErrUndefined   = &kitgo.Error{Code: "undefined"}
+	ErrNoAction    = &kitgo.Error{Code: "no-action"}
+	ErrTerminal    = &kitgo.Error{Code: "terminal"}
+	ErrEventCycle  = &kitgo.Error{Code: "event-cycle"}
+)
+
+// Emitter is an Action declaring the events it may return, Validate follows them to find cycles.
+// The events of any other Action are unknown, so Validate assumes it emits none.
+type Emitter interface {
+	Action
+	Emits() []EventType
+}
+
+// Validate analyses the states of a machine started in initial, every problem is reported at once
+// as a list of *kitgo.Error whose Path is the offending state, nil when there is none:
+//   - ErrUndefined for an initial state, a target, a Parent or an Initial child which is not defined
+//   - ErrNoAction for a leaf state without Action, only composite states may have none
+//   - ErrTerminal for a leaf state which handles no event, neither do its ancestors
+//   - ErrUnreachable for a state which cannot be entered from initial
+//   - ErrEventCycle for states whose Emitter actions may emit events forever, SendEvent would not return
+//
+// Only Emitter actions are analysed for ErrEventCycle, a cycle going through a state whose Action
+// is not an Emitter is not reported, implement Emitter to have it checked.
+func Validate(initial StateType, states States) error {
+	g := &validation{Machine: &Machine{states: states, history: map[StateType]StateType{}}, composite: map[StateType]bool{}}
+	for name, state := range states {
+		g.names = append(g.names, name)
+		if state.Parent != "" {
+			g.composite[state.Parent] = true
+		}
+	}
+	sort.Slice(g.names, func(i, j int) bool { return g.names[i] < g.names[j] })
+
+	if _, ok := states[initial]; !ok {
+		g.report(ErrUndefined, initial, "initial state is undefined")
+	}
+	for _, name := range g.names {
+		state := states[name]
+		if _, ok := states[state.Parent]; !ok && state.Parent != "" {
+			g.report(ErrUndefined, name, "parent %q is undefined", state.Parent)
+		}
+		if _, ok := states[state.Initial]; !ok && state.Initial != "" {
+			g.report(ErrUndefined, name, "initial child %q is undefined", state.Initial)
+		} else if g.composite[name] && state.Initial == "" {
+			g.report(ErrUndefined, name, "composite state has no initial child")
+		}
+		for _, event := range g.events(name) {
+			var targets []StateType
+			for _, t := range state.Transitions[event] {
+				targets = append(targets, t.Target)
+			}
+			if target, ok := state.Events[event]; ok {
+				targets = append(targets, target)
+			}
+			for _, target := range targets {
+				if _, ok := states[target]; !ok {
+					g.report(ErrUndefined, name, "event %q targets undefined state %q", event, target)
+				}
+			}
+		}
+		if g.isLeaf(name) && state.Action == nil {
+			g.report(ErrNoAction, name, "state has no action")
+		}
+		if g.isLeaf(name) && len(g.events(g.path(name)...)) == 0 {
+			g.report(ErrTerminal, name, "state handles no event")
+		}
+	}
+	if _, ok := states[initial]; ok {
+		reachable := g.reach(g.enter(initial), func(leaf StateType) (next []StateType) {
+			for _, event := range g.events(g.path(leaf)...) {
+				next = append(next, g.handle(leaf, event)...)
+			}
+			return next
+		})
+		for _, name := range g.names {
+			if !reachable[name] {
+				g.report(ErrUnreachable, name, "state is unreachable from %q", initial)
+			}
+		}
+	}
+	g.cycles()
+
+	if len(g.errs) == 0 {
+		return nil
+	}
+	return kitgo.NewErrors(g.errs...)
+}
+
+// validation holds the analysis of Validate, it borrows path and enter from Machine.
+type validation struct {
+	*Machine
+	names     []StateType
+	composite map[StateType]bool
+	errs      []error
+}
+
+func (g *validation) report(problem *kitgo.Error, state StateType, format string, args ...interface{}) {
+	g.errs = append(g.errs, &kitgo.Error{Code: problem.Code, Path: string(state), Message: fmt.Sprintf(format, args...)})
+}
+
+func (g *validation) isLeaf(state StateType) bool {
+	return !g.composite[state] && g.states[state].Initial == ""
+}
+
+// events lists the events handled by the states, sorted.
+func (g *validation) events(states ...StateType) []EventType {
+	set := map[EventType]bool{}
+	for _, name := range states {
+		for event := range g.states[name].Events {
+			set[event] = true
+		}
+		for event := range g.states[name].Transitions {
+			set[event] = true
+		}
+	}
+	events := make([]EventType, 0, len(set))
+	for event := range set {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	return events
+}
+
+// handle lists the possible targets of an event sent in a state, as in nextState the event
+// bubbles up while guards may fail, until an unguarded transition or Events handles it.
+func (g *validation) handle(state StateType, event EventType) (targets []StateType) {
+	path := g.path(state)
+	for i := len(path) - 1; i >= 0; i-- {
+		s := g.states[path[i]]
+		for _, t := range s.Transitions[event] {
+			targets = append(targets, t.Target)
+			if t.Guard == nil {
+				return targets
+			}
+		}
+		if target, ok := s.Events[event]; ok {
+			return append(targets, target)
+		}
+	}
+	return targets
+}
+
+// reach marks the states entered from a leaf, next gives the targets left from a leaf.
+func (g *validation) reach(from StateType, next func(leaf StateType) []StateType) map[StateType]bool {
+	reached, leaves := map[StateType]bool{}, map[StateType]bool{}
+	queue := []StateType{from}
+	for len(queue) > 0 {
+		leaf := queue[0]
+		queue = queue[1:]
+		if leaves[leaf] {
+			continue
+		}
+		leaves[leaf] = true
+		for _, name := range g.path(leaf) {
+			reached[name] = true
+		}
+		for _, target := range next(leaf) {
+			queue = append(queue, g.enter(target))
+		}
+	}
+	return reached
+}
+
+// cycles reports each group of states whose actions emit events back to one another, an action
+// which is not an Emitter ends the chain as if it emitted no event.
+func (g *validation) cycles() {
+	emitted := func(leaf StateType) (next []StateType) {
+		if emitter, ok := g.states[leaf].Action.(Emitter); ok {
+			for _, event := range emitter.Emits() {
+				next = append(next, g.handle(leaf, event)...)
+			}
+		}
+		return next
+	}
+	reach := map[StateType]map[StateType]bool{}
+	for _, name := range g.names {
+		reach[name] = map[StateType]bool{}
+		for _, target := range emitted(name) {
+			for state := range g.reach(g.enter(target), emitted) {
+				reach[name][state] = true
+			}
+		}
+	}
+	reported := map[StateType]bool{}
+	for _, name := range g.names {
+		if reported[name] || !reach[name][name] || !g.isLeaf(name) {
+			continue
+		}
+		var cycle []string
+		for _, other := range g.names {
+			if reach[name][other] && reach[other][name] && g.isLeaf(other) {
+				reported[other] = true
+				cycle = append(cycle, string(other))
+			}
+		}
+		g.report(ErrEventCycle, name, "actions emit events forever through %s", strings.Join(cycle, ", "))
+	}
+}
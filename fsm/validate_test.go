@@ -0,0 +1,69 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	"github.com/hokonco/kitgo/fsm"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_fsm_validate(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	Expect(fsm.Validate(StateOff, fsm.States{
+		StateOff: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventOn: StateOn}},
+		StateOn:  fsm.State{Action: &OnAction{}, Events: fsm.Events{EventOff: StateOff}},
+	})).To(Succeed())
+
+	never := func(context.Context) bool { return false }
+	err := fsm.Validate(StateOrdered, fsm.States{
+		StateOrdered: fsm.State{Action: &OffAction{}, Events: fsm.Events{EventShip: StateShipping}, Transitions: fsm.Transitions{
+			EventCancel: {{Guard: never, Target: "Lost"}, {Target: StateCancelled}},
+		}},
+		StateShipping:  fsm.State{Initial: StatePacked, Events: fsm.Events{EventHold: StateOnHold}},
+		StatePacked:    fsm.State{Action: emitAction{EventDispatch}, Parent: StateShipping, Events: fsm.Events{EventDispatch: StateInTransit}},
+		StateInTransit: fsm.State{Action: emitAction{EventHold}, Parent: StateShipping},
+		StateOnHold:    fsm.State{Action: emitAction{EventResume}, Events: fsm.Events{EventResume: StateShipping}},
+		StateCancelled: fsm.State{Action: emitAction{EventCancel}},
+		"Orphan":       fsm.State{Parent: "Ghost"},
+		"Box":          fsm.State{},
+		"Inner":        fsm.State{Action: &OffAction{}, Parent: "Box", Events: fsm.Events{EventShip: "Box"}},
+		"Crate":        fsm.State{Action: &OffAction{}, Initial: "Ghost", Events: fsm.Events{EventShip: "Crate"}},
+	})
+	var messages []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		messages = append(messages, err.Error())
+	}
+	Expect(messages).To(Equal([]string{
+		`Box: composite state has no initial child`,
+		`Cancelled: state handles no event`,
+		`Crate: initial child "Ghost" is undefined`,
+		`Ordered: event "Cancel" targets undefined state "Lost"`,
+		`Orphan: parent "Ghost" is undefined`,
+		`Orphan: state has no action`,
+		`Orphan: state handles no event`,
+		`Box: state is unreachable from "Ordered"`,
+		`Crate: state is unreachable from "Ordered"`,
+		`Inner: state is unreachable from "Ordered"`,
+		`Orphan: state is unreachable from "Ordered"`,
+		`InTransit: actions emit events forever through InTransit, OnHold, Packed`,
+	}))
+	Expect(errors.Is(err, fsm.ErrEventCycle)).To(BeTrue())
+	var e *kitgo.Error
+	Expect(errors.As(err, &e)).To(BeTrue())
+	Expect(e).To(Equal(&kitgo.Error{Code: "undefined", Path: "Box", Message: "composite state has no initial child"}))
+
+	err = fsm.Validate("Unknown", nil)
+	Expect(errors.Is(err, fsm.ErrUndefined)).To(BeTrue())
+	Expect(err).To(MatchError("Unknown: initial state is undefined"))
+}
+
+// emitAction returns the first event it declares.
+type emitAction []fsm.EventType
+
+func (a emitAction) Execute(ctx context.Context) fsm.EventType { return a[0] }
+func (a emitAction) Emits() []fsm.EventType                    { return a }
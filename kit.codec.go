@@ -0,0 +1,244 @@
+package kitgo
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+var Hex = hex_{}
+
+type hex_ struct{}
+
+// Std encode to lowercase hexadecimal, both cases are accepted when decoding
+func (hex_) Std() HexWrapper { return HexWrapper{} }
+
+type HexWrapper struct{}
+
+func (x HexWrapper) BtoA(b []byte) []byte {
+	enc := make([]byte, hex.EncodedLen(len(b)))
+	hex.Encode(enc, b)
+	return enc
+}
+
+// AtoB return the decoded bytes, the bytes decoded before an invalid input are
+// returned as is, use Decode to get the error
+func (x HexWrapper) AtoB(b []byte) []byte {
+	dec, _ := x.Decode(b)
+	return dec
+}
+
+// Decode return the decoded bytes and the error of an invalid input
+func (x HexWrapper) Decode(b []byte) ([]byte, error) {
+	dec := make([]byte, hex.DecodedLen(len(b)))
+	n, err := hex.Decode(dec, b)
+	return dec[:n], err
+}
+
+// NewEncoder return a stream encoder writing to w, Close is a no-op as hex has
+// no partial block
+func (x HexWrapper) NewEncoder(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{hex.NewEncoder(w)}
+}
+
+// NewDecoder return a stream decoder reading from r
+func (x HexWrapper) NewDecoder(r io.Reader) io.Reader { return hex.NewDecoder(r) }
+
+var Base32 = base32_{}
+
+type base32_ struct{}
+
+func (base32_) Std() Base32Wrapper { return Base32Wrapper{base32.StdEncoding} }
+func (base32_) Hex() Base32Wrapper { return Base32Wrapper{base32.HexEncoding} }
+func (base32_) RawStd() Base32Wrapper {
+	return Base32Wrapper{base32.StdEncoding.WithPadding(base32.NoPadding)}
+}
+func (base32_) RawHex() Base32Wrapper {
+	return Base32Wrapper{base32.HexEncoding.WithPadding(base32.NoPadding)}
+}
+
+type Base32Wrapper struct{ b32 *base32.Encoding }
+
+func (x Base32Wrapper) BtoA(b []byte) []byte {
+	enc := make([]byte, x.b32.EncodedLen(len(b)))
+	x.b32.Encode(enc, b)
+	return enc
+}
+
+// AtoB return the decoded bytes, the bytes decoded before an invalid input are
+// returned as is, use Decode to get the error
+func (x Base32Wrapper) AtoB(b []byte) []byte {
+	dec, _ := x.Decode(b)
+	return dec
+}
+
+// Decode return the decoded bytes and the error of an invalid input
+func (x Base32Wrapper) Decode(b []byte) ([]byte, error) {
+	dec := make([]byte, x.b32.DecodedLen(len(b)))
+	n, err := x.b32.Decode(dec, b)
+	return dec[:n], err
+}
+
+// NewEncoder return a stream encoder writing to w, Close should be called to
+// flush the partially written block
+func (x Base32Wrapper) NewEncoder(w io.Writer) io.WriteCloser { return base32.NewEncoder(x.b32, w) }
+
+// NewDecoder return a stream decoder reading from r
+func (x Base32Wrapper) NewDecoder(r io.Reader) io.Reader { return base32.NewDecoder(x.b32, r) }
+
+var Base58 = base58_{}
+
+type base58_ struct{}
+
+// Bitcoin is the alphabet used by bitcoin addresses and IPFS
+func (base58_) Bitcoin() Base58Wrapper {
+	return newBase58("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+}
+
+// Flickr is the alphabet used by flickr short urls
+func (base58_) Flickr() Base58Wrapper {
+	return newBase58("123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ")
+}
+
+// Ripple is the alphabet used by ripple addresses
+func (base58_) Ripple() Base58Wrapper {
+	return newBase58("rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz")
+}
+
+// Base58Wrapper encode bytes as a big-endian number in base 58, each leading
+// zero byte is encoded as the first character of the alphabet
+//
+// Base58 is not a block encoding, so the stream encoder & decoder buffer the
+// whole payload, they exist for API parity with Base64Wrapper
+type Base58Wrapper struct {
+	alphabet string
+	index    *[256]int8
+}
+
+func newBase58(alphabet string) Base58Wrapper {
+	index := new([256]int8)
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		index[alphabet[i]] = int8(i)
+	}
+	return Base58Wrapper{alphabet, index}
+}
+
+func (x Base58Wrapper) BtoA(b []byte) []byte {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	// log(256) / log(58) ~ 1.366, digits are stored little-endian
+	digits := make([]byte, 0, (len(b)-zeros)*138/100+1)
+	for _, c := range b[zeros:] {
+		carry := int(c)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+	enc := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		enc[i] = x.alphabet[0]
+	}
+	for i, d := range digits {
+		enc[len(enc)-1-i] = x.alphabet[d]
+	}
+	return enc
+}
+
+// AtoB return the decoded bytes, nil is returned for an invalid input, use
+// Decode to get the error
+func (x Base58Wrapper) AtoB(b []byte) []byte {
+	dec, _ := x.Decode(b)
+	return dec
+}
+
+// Decode return the decoded bytes and the error of an invalid input
+func (x Base58Wrapper) Decode(b []byte) ([]byte, error) {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == x.alphabet[0] {
+		zeros++
+	}
+	// log(58) / log(256) ~ 0.733, bytes are stored little-endian
+	buf := make([]byte, 0, (len(b)-zeros)*733/1000+1)
+	for i, c := range b[zeros:] {
+		carry := int(x.index[c])
+		if carry < 0 {
+			return nil, fmt.Errorf("kitgo: illegal base58 data at input byte %d", zeros+i)
+		}
+		for j := range buf {
+			carry += int(buf[j]) * 58
+			buf[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			buf = append(buf, byte(carry))
+			carry >>= 8
+		}
+	}
+	dec := make([]byte, zeros+len(buf))
+	for i, c := range buf {
+		dec[len(dec)-1-i] = c
+	}
+	return dec, nil
+}
+
+// NewEncoder return an encoder writing to w on Close
+func (x Base58Wrapper) NewEncoder(w io.Writer) io.WriteCloser {
+	return &base58Encoder{x: x, w: w}
+}
+
+// NewDecoder return a decoder reading the whole r on the first Read
+func (x Base58Wrapper) NewDecoder(r io.Reader) io.Reader {
+	return &base58Decoder{x: x, r: r}
+}
+
+type base58Encoder struct {
+	x   Base58Wrapper
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (e *base58Encoder) Write(p []byte) (int, error) { return e.buf.Write(p) }
+func (e *base58Encoder) Close() error {
+	_, err := e.w.Write(e.x.BtoA(e.buf.Bytes()))
+	e.buf.Reset()
+	return err
+}
+
+type base58Decoder struct {
+	x   Base58Wrapper
+	r   io.Reader
+	dec io.Reader
+	err error
+}
+
+func (d *base58Decoder) Read(p []byte) (int, error) {
+	if d.dec == nil && d.err == nil {
+		b, err := ioutil.ReadAll(d.r)
+		if err == nil {
+			b, err = d.x.Decode(bytes.TrimSpace(b))
+		}
+		d.dec, d.err = bytes.NewReader(b), err
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.dec.Read(p)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,101 @@
+package kitgo_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_codec(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	type codec interface {
+		BtoA([]byte) []byte
+		AtoB([]byte) []byte
+		Decode([]byte) ([]byte, error)
+		NewEncoder(io.Writer) io.WriteCloser
+		NewDecoder(io.Reader) io.Reader
+	}
+	stream := func(c codec, b []byte) ([]byte, []byte) {
+		buf := new(bytes.Buffer)
+		w := c.NewEncoder(buf)
+		for i := range b {
+			_, err := w.Write(b[i : i+1])
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+		enc := append([]byte(nil), buf.Bytes()...)
+		dec, err := ioutil.ReadAll(c.NewDecoder(buf))
+		Expect(err).NotTo(HaveOccurred())
+		return enc, dec
+	}
+	plain := []byte("\x00\x00hello, world\xff")
+
+	for _, tt := range []struct {
+		name    string
+		c       codec
+		encoded string
+		invalid string
+	}{
+		{"Base64.Std", kitgo.Base64.Std(), "AABoZWxsbywgd29ybGT/", "AAB!"},
+		{"Base64.URL", kitgo.Base64.URL(), "AABoZWxsbywgd29ybGT_", "AAB!"},
+		{"Base64.RawStd", kitgo.Base64.RawStd(), "AABoZWxsbywgd29ybGT/", "AAB!"},
+		{"Base32.Std", kitgo.Base32.Std(), "AAAGQZLMNRXSYIDXN5ZGYZH7", "AAA1"},
+		{"Base32.Hex", kitgo.Base32.Hex(), "0006GPBCDHNIO83NDTP6OP7V", "000Z"},
+		{"Base32.RawStd", kitgo.Base32.RawStd(), "AAAGQZLMNRXSYIDXN5ZGYZH7", "AAA1"},
+		{"Base32.RawHex", kitgo.Base32.RawHex(), "0006GPBCDHNIO83NDTP6OP7V", "000Z"},
+		{"Hex.Std", kitgo.Hex.Std(), "000068656c6c6f2c20776f726c64ff", "00zz"},
+		{"Base58.Bitcoin", kitgo.Base58.Bitcoin(), "119hLF3DC74NBvQxntWi", "110"},
+		{"Base58.Flickr", kitgo.Base58.Flickr(), "119Gkf3dc74nbVpXMTvH", "110"},
+		{"Base58.Ripple", kitgo.Base58.Ripple(), "rr96LEsDUfh4BvQx8tW5", "rr0"},
+	} {
+		Expect(string(tt.c.BtoA(plain))).To(Equal(tt.encoded), tt.name)
+		Expect(tt.c.AtoB([]byte(tt.encoded))).To(Equal(plain), tt.name)
+		dec, err := tt.c.Decode([]byte(tt.encoded))
+		Expect(err).NotTo(HaveOccurred(), tt.name)
+		Expect(dec).To(Equal(plain), tt.name)
+
+		enc, dec := stream(tt.c, plain)
+		Expect(string(enc)).To(Equal(tt.encoded), tt.name)
+		Expect(dec).To(Equal(plain), tt.name)
+
+		_, err = tt.c.Decode([]byte(tt.invalid))
+		Expect(err).To(HaveOccurred(), tt.name)
+		_, err = ioutil.ReadAll(tt.c.NewDecoder(strings.NewReader(tt.invalid)))
+		Expect(err).To(HaveOccurred(), tt.name)
+
+		Expect(tt.c.BtoA(nil)).To(BeEmpty(), tt.name)
+		Expect(tt.c.AtoB(nil)).To(BeEmpty(), tt.name)
+	}
+
+	t.Run("no trailing zero", func(t *testing.T) {
+		Expect(kitgo.Base64.Std().AtoB([]byte("YQ=="))).To(Equal([]byte("a")))
+		Expect(kitgo.Base32.Std().AtoB([]byte("ME======"))).To(Equal([]byte("a")))
+	})
+	t.Run("partial decode", func(t *testing.T) {
+		dec, err := kitgo.Base64.Std().Decode([]byte("aGVsbG8!"))
+		Expect(err).To(MatchError("illegal base64 data at input byte 7"))
+		Expect(dec).To(Equal([]byte("hel")))
+		dec, err = kitgo.Base58.Bitcoin().Decode([]byte("2NEpo7TZRl"))
+		Expect(err).To(MatchError("kitgo: illegal base58 data at input byte 9"))
+		Expect(dec).To(BeNil())
+	})
+	t.Run("stream error", func(t *testing.T) {
+		errRead := errors.New("read")
+		_, err := ioutil.ReadAll(kitgo.Base58.Bitcoin().NewDecoder(io.MultiReader(strings.NewReader("2NEp"), iotest{errRead})))
+		Expect(err).To(Equal(errRead))
+		Expect(kitgo.Base58.Bitcoin().NewDecoder(strings.NewReader(" 2NEpo7TZRRrLZSi2U \n"))).
+			To(WithTransform(func(r io.Reader) string { b, _ := ioutil.ReadAll(r); return string(b) }, Equal("Hello World!")))
+	})
+}
+
+type iotest struct{ err error }
+
+func (r iotest) Read([]byte) (int, error) { return 0, r.err }
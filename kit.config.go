@@ -0,0 +1,574 @@
+package kitgo
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var Config config_
+
+type config_ struct{}
+
+// ConfigSource is the layered sources of Config.Load, from the lowest to the
+// highest precedence: the `default` tag, File, the environment variables and
+// the command-line flags
+type ConfigSource struct {
+	// File is a json file, an empty File is skipped
+	File string
+
+	// EnvPrefix is prepended to the name of each environment variable
+	EnvPrefix string
+
+	// LookupEnv default to os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+
+	// FlagSet receive a flag for each field and parse Args, default to
+	// os.Args[1:], a nil FlagSet skip the command-line flags, the flags are
+	// defined once so a FlagSet is not reused by a second Load, which would
+	// panic as the flags are redefined
+	FlagSet *flag.FlagSet
+	Args    []string
+}
+
+// Load populate dst, a pointer to struct, from src, each exported field is
+// configured by these tags:
+//
+//	config:"name,required,secret" // name default to the field name
+//	default:"value"               // the lowest layer
+//	env:"NAME"                    // default to the upper snake case of the path
+//	flag:"name"                   // default to the kebab case of the path
+//
+// A nested struct is a path segment, e.g. SQL.MaxOpenConns is the key
+// "maxopenconns" of the object "sql" in File (case-insensitive), the variable
+// EnvPrefix+"SQL_MAX_OPEN_CONNS" and the flag "-sql.max-open-conns", use "-"
+// as name to skip a field or a source
+//
+// A value is parsed from a string by its encoding.TextUnmarshaler, else
+// time.Duration accept time.ParseDuration or nanoseconds, integers accept a
+// size such as "64KB" or "1.5MiB" (KB is 1000 and KiB is 1024 bytes), and a
+// slice is separated by comma
+//
+// A required field is satisfied by any layer, even with a zero value such as
+// an explicit "false", or by a non-zero value already populated in dst
+//
+// Every problem is returned as errorList of *Error with the path of the field
+// and the code "invalid" or "required"
+func (config_) Load(dst interface{}, src *ConfigSource) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("kitgo: config destination should be a non-nil pointer to struct")
+	}
+	if src == nil {
+		src = &ConfigSource{}
+	}
+	fields, commits := configFields(rv.Elem(), configPath{})
+
+	var errs errorList
+	for _, f := range fields {
+		if f.hasDefault {
+			errs = errs.Append(f.set("default", f.name, f.def))
+		}
+	}
+	if src.File != "" {
+		errs = errs.Append(configFile(src.File, fields))
+	}
+	lookupEnv := src.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if s, ok := lookupEnv(src.EnvPrefix + f.env); ok {
+			errs = errs.Append(f.set("env", src.EnvPrefix+f.env, s))
+		}
+	}
+	if src.FlagSet != nil {
+		errs = errs.Append(configFlags(src.FlagSet, src.Args, fields))
+	}
+
+	for _, commit := range commits {
+		commit()
+	}
+	for _, f := range fields {
+		if f.required && !f.isSet && f.value.IsZero() && f.present() {
+			errs = errs.Append(&Error{Code: "required", Path: f.name, Message: "is required"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// String format v like the verb %+v, the value of a non-empty field tagged
+// `config:",secret"` is redacted, it could be used to implement fmt.Stringer,
+// beware that the method is promoted to the structs embedding that type
+func (config_) String(v interface{}) string {
+	return configString(reflect.ValueOf(v))
+}
+
+const configRedacted = "******"
+
+// Secret is a string redacted by fmt whatever the verb, e.g. a password or a
+// data source name, so that printing the struct holding it does not leak it,
+// use string(s) to read the value
+type Secret string
+
+// String return the redacted value, or an empty string when s is empty
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return configRedacted
+}
+
+// GoString implement fmt.GoStringer for the verb %#v
+func (s Secret) GoString() string { return strconv.Quote(s.String()) }
+
+// Format implement fmt.Formatter, every verb print the redacted value
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'q', verb == 'v' && f.Flag('#'):
+		fmt.Fprint(f, s.GoString())
+	default:
+		fmt.Fprint(f, s.String())
+	}
+}
+
+type configField struct {
+	name       string
+	env, flag  string
+	def        string
+	hasDefault bool
+	required   bool
+	isSet      bool
+	value      reflect.Value
+	groups     []configGroup
+}
+
+// present report whether every nil pointer to struct above f is assigned, a
+// required field is only required when its parent is present
+func (f *configField) present() bool {
+	for _, g := range f.groups {
+		if !g.isSet() {
+			return false
+		}
+	}
+	return true
+}
+
+// configGroup is the fields of a nil pointer to struct
+type configGroup []*configField
+
+func (g configGroup) isSet() bool {
+	for _, f := range g {
+		if f.isSet {
+			return true
+		}
+	}
+	return false
+}
+
+// set parse s into the field, source & key describe where s come from
+func (f *configField) set(source, key, s string) error {
+	if err := configParse(f.value, s); err != nil {
+		return &Error{
+			Code:    "invalid",
+			Path:    f.name,
+			Message: fmt.Sprintf("cannot parse %s %s as %s: %v", source, key, f.value.Type(), err),
+			Meta:    Dict{"source": source, "key": key},
+			Err:     err,
+		}
+	}
+	f.isSet = true
+	return nil
+}
+
+// configPath is the path of the parent struct of a field, a "-" segment of
+// env or flag skip that source for all the fields below
+type configPath struct {
+	names, envs, flags []string
+	types              []reflect.Type
+}
+
+// configJoin join the path and s, an empty string is returned when any of
+// them is "-"
+func configJoin(path []string, s, sep string) string {
+	list := append(append(make([]string, 0, len(path)+1), path...), s)
+	for _, seg := range list {
+		if seg == "-" {
+			return ""
+		}
+	}
+	return strings.Join(list, sep)
+}
+
+// configFields collect the settable fields of v, a nil pointer to struct is
+// allocated and only assigned by the returned commits when any of its field
+// is set, a struct that contains itself is skipped
+func configFields(v reflect.Value, path configPath) ([]*configField, []func()) {
+	var fields []*configField
+	var commits []func()
+	t := v.Type()
+	for _, parent := range path.types {
+		if parent == t {
+			return nil, nil
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		opts := strings.Split(sf.Tag.Get("config"), ",")
+		if opts[0] == "-" {
+			continue
+		}
+		name := opts[0]
+		if name == "" {
+			name = sf.Name
+		}
+		env, flg := configSnake(name, '_', unicode.ToUpper), configSnake(name, '-', unicode.ToLower)
+		if tag, ok := sf.Tag.Lookup("env"); ok {
+			env = tag
+		}
+		if tag, ok := sf.Tag.Lookup("flag"); ok {
+			flg = tag
+		}
+		fv := v.Field(i)
+
+		if configLeaf(fv.Type()) {
+			f := &configField{
+				name:  configJoin(path.names, name, "."),
+				env:   configJoin(path.envs, env, "_"),
+				flag:  configJoin(path.flags, flg, "."),
+				value: fv,
+			}
+			f.def, f.hasDefault = sf.Tag.Lookup("default")
+			for _, opt := range opts[1:] {
+				f.required = f.required || opt == "required"
+			}
+			fields = append(fields, f)
+			continue
+		}
+
+		elem := fv
+		if fv.Kind() == reflect.Ptr {
+			if elem = fv; fv.IsNil() {
+				elem = reflect.New(fv.Type().Elem())
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			continue
+		}
+		sub := path
+		sub.types = append(append([]reflect.Type(nil), path.types...), t)
+		// the fields of an embedded struct are promoted unless it is named
+		if !sf.Anonymous || opts[0] != "" {
+			sub.names = append(append([]string(nil), path.names...), name)
+			sub.envs = append(append([]string(nil), path.envs...), env)
+			sub.flags = append(append([]string(nil), path.flags...), flg)
+		}
+		subFields, subCommits := configFields(elem, sub)
+		fields, commits = append(fields, subFields...), append(commits, subCommits...)
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			group := configGroup(subFields)
+			for _, f := range subFields {
+				f.groups = append(f.groups, group)
+			}
+			commits = append(commits, func() {
+				if group.isSet() {
+					fv.Set(elem.Addr())
+				}
+			})
+		}
+	}
+	return fields, commits
+}
+
+// configLeaf report whether t is parsed from a string
+func configLeaf(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(typeTextMarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Ptr, reflect.Slice:
+		return configLeaf(t.Elem())
+	}
+	return false
+}
+
+// configParse set v from s, see Config.Load
+func configParse(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := configParse(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.Slice:
+		var parts []string
+		if s = strings.TrimSpace(s); s != "" {
+			parts = strings.Split(s, ",")
+		}
+		list := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i := range parts {
+			if err := configParse(list.Index(i), strings.TrimSpace(parts[i])); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(list)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		var err error
+		if v.Type() == typeDuration {
+			if n, err = strconv.ParseInt(s, 10, 64); err != nil {
+				var d time.Duration
+				d, err = time.ParseDuration(s)
+				n = int64(d)
+			}
+		} else {
+			n, err = configSize(s)
+		}
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%s overflows %s", s, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := configSize(s)
+		if err != nil {
+			return err
+		}
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s overflows %s", s, v.Type())
+		}
+		v.SetUint(uint64(n))
+	}
+	return nil
+}
+
+var configSizeUnits = []struct {
+	unit string
+	size float64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+	{"b", 1},
+}
+
+// configSize parse an integer with an optional size unit
+func configSize(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	lower := strings.ToLower(strings.TrimSpace(s))
+	for _, u := range configSizeUnits {
+		if !strings.HasSuffix(lower, u.unit) {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(lower[:len(lower)-len(u.unit)]), 64)
+		if err != nil {
+			break
+		}
+		if n *= u.size; n >= math.MaxInt64 || n <= math.MinInt64 {
+			return 0, fmt.Errorf("size %q is out of range", s)
+		}
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("invalid size %q", s)
+}
+
+// configFile set fields from the json file, null is skipped
+func configFile(path string, fields []*configField) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var root Dict
+	if err := JSON.Unmarshal(b, &root); err != nil {
+		return fmt.Errorf("kitgo: config file %s: %w", path, err)
+	}
+	var errs errorList
+	for _, f := range fields {
+		v := configLookup(root, strings.Split(f.name, "."))
+		if v == nil {
+			continue
+		}
+		list, isList := v.([]interface{})
+		if !isList || f.value.Kind() != reflect.Slice {
+			list = []interface{}{v}
+		}
+		parts := make([]string, len(list))
+		var err error
+		for i := range list {
+			if parts[i], err = toString(f.name, list[i]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			errs = errs.Append(&Error{
+				Code:    "invalid",
+				Path:    f.name,
+				Message: fmt.Sprintf("cannot use %T of file %s as %s", v, path, f.value.Type()),
+				Err:     err,
+			})
+			continue
+		}
+		errs = errs.Append(f.set("file", path, strings.Join(parts, ",")))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// configLookup find the value of path in v, the keys are case-insensitive
+func configLookup(v interface{}, path []string) interface{} {
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if d, isDict := v.(Dict); isDict {
+			m, ok = d, true
+		}
+		if !ok {
+			return nil
+		}
+		if v, ok = m[key]; ok {
+			continue
+		}
+		for k := range m {
+			if strings.EqualFold(k, key) {
+				v = m[k]
+			}
+		}
+	}
+	return v
+}
+
+type configFlag struct {
+	isBool bool
+	value  string
+}
+
+func (f *configFlag) String() string     { return "" }
+func (f *configFlag) Set(s string) error { f.value = s; return nil }
+func (f *configFlag) IsBoolFlag() bool   { return f.isBool }
+
+// configFlags define a flag for each field then parse args
+func configFlags(fs *flag.FlagSet, args []string, fields []*configField) error {
+	values := map[string]*configFlag{}
+	byName := map[string]*configField{}
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		v := &configFlag{isBool: f.value.Kind() == reflect.Bool}
+		values[f.flag], byName[f.flag] = v, f
+		fs.Var(v, f.flag, f.name)
+	}
+	if args == nil {
+		args = os.Args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var errs errorList
+	fs.Visit(func(fl *flag.Flag) {
+		if f, ok := byName[fl.Name]; ok {
+			errs = errs.Append(f.set("flag", "-"+fl.Name, values[fl.Name].value))
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// configSnake convert "ConnMaxIdleTime" to e.g. "CONN_MAX_IDLE_TIME"
+func configSnake(name string, sep rune, mapping func(rune) rune) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+			(i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1]))) {
+			b.WriteRune(sep)
+		}
+		b.WriteRune(mapping(r))
+	}
+	return b.String()
+}
+
+// configString format v like %+v with the secret fields redacted
+func configString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "<nil>"
+		}
+		return "&" + configString(v.Elem())
+	case reflect.Struct:
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+	t := v.Type()
+	var parts []string
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), v.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		var s string
+		opts := strings.Split(sf.Tag.Get("config"), ",")
+		secret := false
+		for _, opt := range opts[1:] {
+			secret = secret || opt == "secret"
+		}
+		switch _, ok := fv.Interface().(fmt.Stringer); {
+		case secret && !fv.IsZero():
+			s = configRedacted
+		case ok:
+			s = fmt.Sprint(fv.Interface())
+		default:
+			s = configString(fv)
+		}
+		parts = append(parts, sf.Name+":"+s)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,288 @@
+package kitgo_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_config(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	type Region struct{ Region string }
+	type appConfig struct {
+		Region
+		Name       string        `config:"name,required"`
+		Debug      bool          `flag:"debug"`
+		Timeout    time.Duration `default:"5s"`
+		MaxBody    int64         `default:"1MiB"`
+		Ratio      float64
+		Tags       []string
+		Ports      []uint16
+		Since      time.Time
+		Level      *int
+		SQL        kitgo.SQLConfig            `config:"sql"`
+		SMTP       *kitgo.SMTPConfig          `config:"smtp"`
+		Prometheus *kitgo.PrometheusConfig    `env:"-" flag:"-"`
+		Custom     string                     `env:"CUSTOM_VAR" flag:"custom"`
+		Skip       string                     `config:"-"`
+		Func       func()                     `default:"ignored"`
+		Map        map[string]string          `default:"ignored"`
+		Options    *struct{ Password string } `config:"options,secret"`
+		Graphics   *kitgo.GraphicsConfig      `env:"GFX"`
+		Nested     struct {
+			Key string `env:"-"`
+		}
+		unexported string
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+	quiet := func(fs *flag.FlagSet) *flag.FlagSet { fs.SetOutput(ioutil.Discard); return fs }
+	env := func(kv ...string) func(string) (string, bool) {
+		m := map[string]string{}
+		for i := 0; i+1 < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return func(k string) (string, bool) { v, ok := m[k]; return v, ok }
+	}
+
+	t.Run("layers", func(t *testing.T) {
+		file := write("config.json", `{
+			"name": "from-file",
+			"ratio": 0.5,
+			"tags": ["a", "b"],
+			"ports": [80, 443],
+			"SQL": {"driverName": "mysql", "dataSourceName": "user:pass@/db", "connMaxIdleTime": "1m", "maxOpenConns": 10},
+			"region": "id",
+			"custom": null
+		}`)
+		var conf appConfig
+		err := kitgo.Config.Load(&conf, &kitgo.ConfigSource{
+			File:      file,
+			EnvPrefix: "APP_",
+			LookupEnv: env(
+				"APP_NAME", "from-env",
+				"APP_SQL_MAX_IDLE_CONNS", "2KB",
+				"APP_SQL_CONN_MAX_LIFETIME", "1000000000",
+				"APP_SMTP_ADDR", "localhost:25",
+				"APP_CUSTOM_VAR", "custom",
+				"APP_SINCE", "2021-01-02T03:04:05Z",
+				"APP_PROMETHEUS_NAMESPACE", "ignored",
+				"APP_NESTED_KEY", "ignored",
+				"APP_GFX_JPEG_QUALITY", "90",
+			),
+			FlagSet: quiet(flag.NewFlagSet("app", flag.ContinueOnError)),
+			Args:    []string{"-debug", "-name", "from-flag", "-level", "3", "-prometheus.namespace", "kitgo"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("flag provided but not defined: -prometheus.namespace"))
+
+		conf = appConfig{}
+		Expect(kitgo.Config.Load(&conf, &kitgo.ConfigSource{
+			File:      file,
+			EnvPrefix: "APP_",
+			LookupEnv: env(
+				"APP_NAME", "from-env",
+				"APP_SQL_MAX_IDLE_CONNS", "2KB",
+				"APP_SQL_CONN_MAX_LIFETIME", "1000000000",
+				"APP_SMTP_ADDR", "localhost:25",
+				"APP_CUSTOM_VAR", "custom",
+				"APP_SINCE", "2021-01-02T03:04:05Z",
+				"APP_PROMETHEUS_NAMESPACE", "ignored",
+				"APP_NESTED_KEY", "ignored",
+				"APP_GFX_JPEG_QUALITY", "90",
+			),
+			FlagSet: flag.NewFlagSet("app", flag.ContinueOnError),
+			Args:    []string{"-debug", "-name", "from-flag", "-level", "3", "-nested.key", "flag"},
+		})).To(Succeed())
+
+		level := 3
+		Expect(conf.Name).To(Equal("from-flag"))
+		Expect(conf.Debug).To(BeTrue())
+		Expect(conf.Timeout).To(Equal(5 * time.Second))
+		Expect(conf.MaxBody).To(Equal(int64(1 << 20)))
+		Expect(conf.Ratio).To(Equal(0.5))
+		Expect(conf.Tags).To(Equal([]string{"a", "b"}))
+		Expect(conf.Ports).To(Equal([]uint16{80, 443}))
+		Expect(conf.Since).To(Equal(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)))
+		Expect(conf.Level).To(Equal(&level))
+		Expect(conf.Region.Region).To(Equal("id"))
+		Expect(conf.SQL).To(Equal(kitgo.SQLConfig{
+			DriverName:      "mysql",
+			DataSourceName:  "user:pass@/db",
+			ConnMaxIdleTime: time.Minute,
+			ConnMaxLifetime: time.Second,
+			MaxIdleConns:    2000,
+			MaxOpenConns:    10,
+		}))
+		Expect(conf.SMTP).To(Equal(&kitgo.SMTPConfig{Addr: "localhost:25"}))
+		Expect(conf.Prometheus).To(BeNil())
+		Expect(conf.Options).To(BeNil())
+		Expect(conf.Graphics.JPEG.Quality).To(Equal(90))
+		Expect(conf.Graphics.GIF).To(BeNil())
+		Expect(conf.Custom).To(Equal("custom"))
+		Expect(conf.Nested.Key).To(Equal("flag"))
+		Expect(conf.Skip).To(BeEmpty())
+
+		Expect(kitgo.Config.String(conf.SQL)).To(Equal("{DriverName:mysql DataSourceName:****** ConnMaxIdleTime:1m0s ConnMaxLifetime:1s MaxIdleConns:2000 MaxOpenConns:10}"))
+		Expect(kitgo.Config.String(conf.SMTP)).To(Equal("&{PlainAuthIdentity: PlainAuthUsername: PlainAuthPassword: CRAMMD5AuthUsername: CRAMMD5AuthSecret: Addr:localhost:25}"))
+		Expect(kitgo.Config.String(kitgo.SMTPConfig{PlainAuthPassword: "p", CRAMMD5AuthSecret: "s"})).
+			To(Equal("{PlainAuthIdentity: PlainAuthUsername: PlainAuthPassword:****** CRAMMD5AuthUsername: CRAMMD5AuthSecret:****** Addr:}"))
+		// an outer struct embedding a config is formatted as usual
+		Expect(fmt.Sprintf("%+v", struct {
+			kitgo.SQLConfig
+			Port int
+		}{Port: 1})).To(HaveSuffix(" Port:1}"))
+		// the secrets are redacted by fmt whatever the verb
+		sql := kitgo.SQLConfig{DriverName: "mysql", DataSourceName: "user:pass@/db"}
+		for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+			Expect(fmt.Sprintf(format, sql)).NotTo(ContainSubstring("pass"), format)
+			Expect(fmt.Sprintf(format, &kitgo.SMTPConfig{PlainAuthPassword: "pass", CRAMMD5AuthSecret: "pass"})).NotTo(ContainSubstring("pass"), format)
+		}
+		Expect(fmt.Sprintf("%+v", sql)).To(Equal("{DriverName:mysql DataSourceName:****** ConnMaxIdleTime:0s ConnMaxLifetime:0s MaxIdleConns:0 MaxOpenConns:0}"))
+		Expect(fmt.Sprintf("%#v", sql.DataSourceName)).To(Equal(`"******"`))
+		Expect(fmt.Sprint(kitgo.Secret(""))).To(BeEmpty())
+		Expect(string(sql.DataSourceName)).To(Equal("user:pass@/db"))
+		conf.Options = &struct{ Password string }{"p"}
+		s := kitgo.Config.String(&conf)
+		Expect(s).To(HavePrefix("&{Region:{Region:id} Name:from-flag Debug:true Timeout:5s MaxBody:1048576 "))
+		Expect(s).To(ContainSubstring(" SQL:{DriverName:mysql DataSourceName:****** "))
+		Expect(s).To(ContainSubstring(" Prometheus:<nil> "))
+		Expect(s).To(ContainSubstring(" Options:****** "))
+		Expect(s).To(ContainSubstring(" Level:&3 "))
+	})
+	t.Run("errors", func(t *testing.T) {
+		Expect(kitgo.Config.Load(nil, nil)).To(MatchError("kitgo: config destination should be a non-nil pointer to struct"))
+		Expect(kitgo.Config.Load(appConfig{}, nil)).To(HaveOccurred())
+		Expect(kitgo.Config.Load(new(int), nil)).To(HaveOccurred())
+
+		// a required field already populated in dst is satisfied
+		conf := appConfig{Name: "x", SQL: kitgo.SQLConfig{DriverName: "mysql", DataSourceName: "dsn"}}
+		Expect(kitgo.Config.Load(&conf, nil)).To(Succeed())
+
+		// an explicit zero value from any layer is satisfied
+		flags := &struct {
+			Debug bool `config:",required"`
+			Port  int  `config:",required" default:"0"`
+		}{}
+		Expect(kitgo.Config.Load(flags, &kitgo.ConfigSource{LookupEnv: env("DEBUG", "false")})).To(Succeed())
+		Expect(kitgo.Config.Load(flags, nil)).To(MatchError("Debug: is required"))
+
+		conf = appConfig{}
+		err := kitgo.Config.Load(&conf, nil)
+		Expect(err).To(MatchError("name: is required\nsql.DriverName: is required\nsql.DataSourceName: is required"))
+		Expect(errors.Is(err, &kitgo.Error{Code: "required"})).To(BeTrue())
+		// a field of a nil pointer is only required once any sibling is set
+		err = kitgo.Config.Load(&conf, &kitgo.ConfigSource{LookupEnv: env("SMTP_PLAIN_AUTH_USERNAME", "user")})
+		Expect(err).To(MatchError("name: is required\nsql.DriverName: is required\nsql.DataSourceName: is required\nsmtp.Addr: is required"))
+
+		conf = appConfig{}
+		err = kitgo.Config.Load(&conf, &kitgo.ConfigSource{
+			File: write("invalid.json", `{"ratio": "x", "tags": [{}], "ports": [], "sql": {"driverName": {}}, "level": [1]}`),
+			LookupEnv: env(
+				"NAME", "x",
+				"DEBUG", "x",
+				"TIMEOUT", "x",
+				"MAX_BODY", "1XB",
+				"PORTS", "1,-1",
+				"SINCE", "x",
+				"SQL_MAX_OPEN_CONNS", "1e30GB",
+				"SQL_MAX_IDLE_CONNS", "xKB",
+				"SQL_DATA_SOURCE_NAME", "dsn",
+			),
+		})
+		Expect(err).To(HaveOccurred())
+		var e *kitgo.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Code).To(Equal("invalid"))
+		Expect(e.Path).To(Equal("Ratio"))
+		Expect(e.Meta).To(Equal(kitgo.Dict{"source": "file", "key": filepath.Join(dir, "invalid.json")}))
+		Expect(err.Error()).To(Equal("" +
+			`Ratio: cannot parse file ` + filepath.Join(dir, "invalid.json") + ` as float64: strconv.ParseFloat: parsing "x": invalid syntax` + "\n" +
+			`Tags: cannot use []interface {} of file ` + filepath.Join(dir, "invalid.json") + ` as []string` + "\n" +
+			`Level: cannot use []interface {} of file ` + filepath.Join(dir, "invalid.json") + ` as *int` + "\n" +
+			`sql.DriverName: cannot use map[string]interface {} of file ` + filepath.Join(dir, "invalid.json") + ` as string` + "\n" +
+			`Debug: cannot parse env DEBUG as bool: strconv.ParseBool: parsing "x": invalid syntax` + "\n" +
+			`Timeout: cannot parse env TIMEOUT as time.Duration: time: invalid duration "x"` + "\n" +
+			`MaxBody: cannot parse env MAX_BODY as int64: invalid size "1XB"` + "\n" +
+			`Ports: cannot parse env PORTS as []uint16: index 1: -1 overflows uint16` + "\n" +
+			`Since: cannot parse env SINCE as time.Time: parsing time "x" as "2006-01-02T15:04:05Z07:00": cannot parse "x" as "2006"` + "\n" +
+			`sql.MaxIdleConns: cannot parse env SQL_MAX_IDLE_CONNS as int: invalid size "xKB"` + "\n" +
+			`sql.MaxOpenConns: cannot parse env SQL_MAX_OPEN_CONNS as int: size "1e30GB" is out of range` + "\n" +
+			`sql.DriverName: is required`,
+		))
+
+		Expect(kitgo.Config.Load(&conf, &kitgo.ConfigSource{File: filepath.Join(dir, "none.json")})).To(HaveOccurred())
+		Expect(kitgo.Config.Load(&conf, &kitgo.ConfigSource{File: write("syntax.json", "{")})).
+			To(MatchError(ContainSubstring("kitgo: config file " + filepath.Join(dir, "syntax.json"))))
+
+		var small struct {
+			Int8  int8
+			Uint8 uint8
+			Uint  uint
+			Level *int
+		}
+		err = kitgo.Config.Load(&small, &kitgo.ConfigSource{
+			LookupEnv: env("INT8", "1KB", "UINT8", "1KB", "UINT", "x", "LEVEL", "x"),
+			FlagSet:   flag.NewFlagSet("small", flag.ContinueOnError),
+			Args:      []string{"-int8", "x"},
+		})
+		Expect(err).To(MatchError("" +
+			"Int8: cannot parse env INT8 as int8: 1KB overflows int8\n" +
+			"Uint8: cannot parse env UINT8 as uint8: 1KB overflows uint8\n" +
+			"Uint: cannot parse env UINT as uint: invalid size \"x\"\n" +
+			"Level: cannot parse env LEVEL as *int: invalid size \"x\"\n" +
+			"Int8: cannot parse flag -int8 as int8: invalid size \"x\"",
+		))
+	})
+	t.Run("size", func(t *testing.T) {
+		for s, expect := range map[string]int64{
+			"0":       0,
+			"-1":      -1,
+			"10B":     10,
+			"1kb":     1000,
+			"1.5KiB":  1536,
+			"2 MB":    2e6,
+			"1MiB":    1 << 20,
+			"1GB":     1e9,
+			"1GiB":    1 << 30,
+			"1TB":     1e12,
+			"0.5 TiB": 1 << 39,
+		} {
+			var v struct{ Size int64 }
+			Expect(kitgo.Config.Load(&v, &kitgo.ConfigSource{LookupEnv: env("SIZE", s)})).To(Succeed(), s)
+			Expect(v.Size).To(Equal(expect), s)
+		}
+	})
+	t.Run("recursive", func(t *testing.T) {
+		var v node
+		Expect(kitgo.Config.Load(&v, &kitgo.ConfigSource{LookupEnv: env("NAME", "a", "NEXT_NAME", "b")})).To(Succeed())
+		Expect(v).To(Equal(node{Name: "a"}))
+	})
+	t.Run("os", func(t *testing.T) {
+		var v struct{ Home string }
+		Expect(kitgo.Config.Load(&v, &kitgo.ConfigSource{
+			FlagSet: flag.NewFlagSet("os", flag.ContinueOnError),
+			Args:    []string{},
+		})).To(Succeed())
+		// args default to the command line, which hold the test flags here
+		_ = kitgo.Config.Load(&v, &kitgo.ConfigSource{FlagSet: quiet(flag.NewFlagSet("os", flag.ContinueOnError))})
+	})
+}
+
+type node struct {
+	Name string
+	Next *node
+}
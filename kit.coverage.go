@@ -0,0 +1,439 @@
+package kitgo
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var Coverage coverage_
+
+type coverage_ struct{}
+
+// CoverageConfig configure Coverage.Check, a threshold is a ratio between 0
+// and 1 like ShouldCover, a zero threshold is not checked
+type CoverageConfig struct {
+	// Profile is the coverage profile, default to the value of the
+	// -test.coverprofile flag when running as test, otherwise "coverage.out"
+	Profile string
+
+	// Baseline is a json file of the previous []CoverageStat used to compute
+	// the delta of each offender, a missing file is ignored
+	Baseline string
+
+	// WriteBaseline replace Baseline with the current stats after the check
+	WriteBaseline bool
+
+	// Package, File & Function are the default threshold of each kind
+	Package  float64
+	File     float64
+	Function float64
+
+	// Thresholds override the default threshold of the stats whose name match
+	// the pattern (see path.Match), the longest matching pattern is used, e.g.
+	// "github.com/hokonco/kitgo/kit.wrap.*.go" or ".../kit.go:ShouldCover"
+	Thresholds map[string]float64
+
+	// Root is the directory of go.mod used to find the source of each file
+	// to compute the function stats, default to the nearest go.mod upward
+	Root string
+
+	// Output receive the report, default to os.Stdout
+	Output io.Writer
+}
+
+// CoverageBlock is a line of the coverage profile
+type CoverageBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Statements, Count   int
+}
+
+// CoverageProfile is the blocks of a file sorted by position
+type CoverageProfile struct {
+	FileName string
+	Mode     string
+	Blocks   []CoverageBlock
+}
+
+// CoverageStat is the number of covered statements of a package, a file or a
+// function, the Name of a function is the file name followed by ":Func" or
+// ":Type.Method"
+type CoverageStat struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Covered    int    `json:"covered"`
+	Statements int    `json:"statements"`
+}
+
+// Ratio return the coverage between 0 and 1, a stat without statement is
+// fully covered
+func (s CoverageStat) Ratio() float64 {
+	if s.Statements < 1 {
+		return 1
+	}
+	return float64(s.Covered) / float64(s.Statements)
+}
+
+// CoverageOffender is a stat below its threshold, Delta is the change of the
+// ratio since the baseline when HasBaseline is true
+type CoverageOffender struct {
+	CoverageStat
+	Threshold   float64
+	Delta       float64
+	HasBaseline bool
+}
+
+func (o CoverageOffender) String() string {
+	s := fmt.Sprintf("%-8s %5.1f%% < %5.1f%%", o.Kind, o.Ratio()*100, o.Threshold*100)
+	if o.HasBaseline {
+		s += fmt.Sprintf(" (%+.1f%%)", o.Delta*100)
+	} else {
+		s += " (new)"
+	}
+	return s + "  " + o.Name
+}
+
+// Parse read a coverage profile as written by `go test -coverprofile`, the
+// blocks repeated by -count or -coverpkg are merged
+func (coverage_) Parse(r io.Reader) ([]CoverageProfile, error) {
+	type key struct {
+		file string
+		pos  [4]int
+	}
+	var (
+		mode     string
+		files    []string
+		profiles = map[string]*CoverageProfile{}
+		blocks   = map[key]int{}
+	)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "mode: ") {
+			mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		if mode == "" {
+			return nil, fmt.Errorf("kitgo: coverage profile: line %d: missing mode", n)
+		}
+		file, b, err := parseCoverageBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("kitgo: coverage profile: line %d: %w", n, err)
+		}
+		p, ok := profiles[file]
+		if !ok {
+			p = &CoverageProfile{FileName: file, Mode: mode}
+			profiles[file], files = p, append(files, file)
+		}
+		k := key{file, [4]int{b.StartLine, b.StartCol, b.EndLine, b.EndCol}}
+		i, ok := blocks[k]
+		if !ok {
+			blocks[k], p.Blocks = len(p.Blocks), append(p.Blocks, b)
+			continue
+		}
+		if mode == "set" {
+			if b.Count > p.Blocks[i].Count {
+				p.Blocks[i].Count = b.Count
+			}
+		} else {
+			p.Blocks[i].Count += b.Count
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	list := make([]CoverageProfile, len(files))
+	for i, file := range files {
+		list[i] = *profiles[file]
+		sort.Slice(list[i].Blocks, func(a, b int) bool {
+			x, y := list[i].Blocks[a], list[i].Blocks[b]
+			return x.StartLine < y.StartLine || (x.StartLine == y.StartLine && x.StartCol < y.StartCol)
+		})
+	}
+	return list, nil
+}
+
+// Stats return the stats of every package & file, and of every function of
+// the module when root is not empty, root is the directory of go.mod
+func (coverage_) Stats(profiles []CoverageProfile, root string) ([]CoverageStat, error) {
+	var modPath string
+	if root != "" {
+		b, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if f := strings.Fields(line); len(f) == 2 && f[0] == "module" {
+				modPath = strings.Trim(f[1], `"`)
+			}
+		}
+	}
+
+	var stats []CoverageStat
+	pkgs := map[string]int{}
+	for _, p := range profiles {
+		file := CoverageStat{Kind: "file", Name: p.FileName}
+		for _, b := range p.Blocks {
+			file.add(b)
+		}
+		pkg := path.Dir(p.FileName)
+		if _, ok := pkgs[pkg]; !ok {
+			pkgs[pkg] = len(stats)
+			stats = append(stats, CoverageStat{Kind: "package", Name: pkg})
+		}
+		stats[pkgs[pkg]].Covered += file.Covered
+		stats[pkgs[pkg]].Statements += file.Statements
+		stats = append(stats, file)
+
+		// the function stats are only computed for the files of the module
+		if root == "" || !strings.HasPrefix(p.FileName, modPath+"/") {
+			continue
+		}
+		rel := strings.TrimPrefix(p.FileName, modPath+"/")
+		funcs, err := coverageFuncs(filepath.Join(root, filepath.FromSlash(rel)), p)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, funcs...)
+	}
+	return stats, nil
+}
+
+// Check evaluate the thresholds of conf and print the offenders sorted by
+// kind, ratio and name, code is returned when it is non-zero, otherwise 1
+// is returned when there is an offender or an error, so it could be used in
+// TestMain e.g.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(kitgo.Coverage.Check(m.Run(), &kitgo.CoverageConfig{File: 0.9}))
+//	}
+//
+// The profile is written by m.Run only when -coverprofile is set, otherwise
+// Check return code as is
+func (coverage_) Check(code int, conf *CoverageConfig) int {
+	if conf == nil {
+		conf = &CoverageConfig{}
+	}
+	out := conf.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	profile := conf.Profile
+	if profile == "" {
+		profile = "coverage.out"
+		if f := flag.Lookup("test.coverprofile"); f != nil {
+			profile = f.Value.String()
+		}
+	}
+	if code != 0 || profile == "" {
+		return code
+	}
+	offenders, err := Coverage.Evaluate(profile, conf)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "FAIL\tcoverage: %v\n", err)
+		return 1
+	}
+	if len(offenders) < 1 {
+		return code
+	}
+	_, _ = fmt.Fprintf(out, "FAIL\tcoverage: %d below the threshold\n", len(offenders))
+	for _, o := range offenders {
+		_, _ = fmt.Fprintf(out, "\t%s\n", o)
+	}
+	return 1
+}
+
+// Evaluate read profile and return the offenders of the thresholds of conf,
+// the baseline of conf is read and written as configured
+func (coverage_) Evaluate(profile string, conf *CoverageConfig) ([]CoverageOffender, error) {
+	f, err := os.Open(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	profiles, err := Coverage.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	root, funcs := conf.Root, conf.Function > 0
+	for p := range conf.Thresholds {
+		funcs = funcs || strings.Contains(p, ":")
+	}
+	if root == "" && funcs {
+		if root, err = findGoMod("."); err != nil {
+			return nil, err
+		}
+	}
+	stats, err := Coverage.Stats(profiles, root)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := map[string]CoverageStat{}
+	if conf.Baseline != "" {
+		b, err := ioutil.ReadFile(conf.Baseline)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		var list []CoverageStat
+		if len(b) > 0 {
+			if err := JSON.Unmarshal(b, &list); err != nil {
+				return nil, fmt.Errorf("kitgo: coverage baseline: %w", err)
+			}
+		}
+		for _, s := range list {
+			baseline[s.Kind+" "+s.Name] = s
+		}
+	}
+
+	var offenders []CoverageOffender
+	for _, s := range stats {
+		threshold := conf.threshold(s)
+		if threshold <= 0 || s.Ratio() >= threshold {
+			continue
+		}
+		o := CoverageOffender{CoverageStat: s, Threshold: threshold}
+		if prev, ok := baseline[s.Kind+" "+s.Name]; ok {
+			o.Delta, o.HasBaseline = s.Ratio()-prev.Ratio(), true
+		}
+		offenders = append(offenders, o)
+	}
+	kinds := map[string]int{"package": 0, "file": 1, "function": 2}
+	sort.SliceStable(offenders, func(i, j int) bool {
+		x, y := offenders[i], offenders[j]
+		if kinds[x.Kind] != kinds[y.Kind] {
+			return kinds[x.Kind] < kinds[y.Kind]
+		}
+		if x.Ratio() != y.Ratio() {
+			return x.Ratio() < y.Ratio()
+		}
+		return x.Name < y.Name
+	})
+
+	if conf.WriteBaseline && conf.Baseline != "" {
+		b, err := JSON.MarshalIndent(stats, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(conf.Baseline, append(b, '\n'), 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return offenders, nil
+}
+
+func (conf *CoverageConfig) threshold(s CoverageStat) float64 {
+	threshold, pattern := map[string]float64{
+		"package":  conf.Package,
+		"file":     conf.File,
+		"function": conf.Function,
+	}[s.Kind], ""
+	for p, t := range conf.Thresholds {
+		if ok, _ := path.Match(p, s.Name); ok && len(p) >= len(pattern) {
+			if len(p) > len(pattern) || t > threshold {
+				threshold, pattern = t, p
+			}
+		}
+	}
+	return threshold
+}
+
+func (s *CoverageStat) add(b CoverageBlock) {
+	s.Statements += b.Statements
+	if b.Count > 0 {
+		s.Covered += b.Statements
+	}
+}
+
+// parseCoverageBlock parse "name.go:line.column,line.column numberOfStatements count"
+func parseCoverageBlock(line string) (string, CoverageBlock, error) {
+	var b CoverageBlock
+	i := strings.LastIndexByte(line, ':')
+	if i < 0 {
+		return "", b, fmt.Errorf("invalid block %q", line)
+	}
+	file, rest := line[:i], strings.NewReplacer(",", " ", ".", " ").Replace(line[i+1:])
+	f := strings.Fields(rest)
+	if len(f) != 6 {
+		return "", b, fmt.Errorf("invalid block %q", line)
+	}
+	n := make([]int, len(f))
+	for j := range f {
+		v, err := strconv.Atoi(f[j])
+		if err != nil {
+			return "", b, fmt.Errorf("invalid block %q", line)
+		}
+		n[j] = v
+	}
+	return file, CoverageBlock{n[0], n[1], n[2], n[3], n[4], n[5]}, nil
+}
+
+// coverageFuncs return the stat of each function declared in filename
+func coverageFuncs(filename string, p CoverageProfile) ([]CoverageStat, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var stats []CoverageStat
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			typ := fn.Recv.List[0].Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if ident, ok := typ.(*ast.Ident); ok {
+				name = ident.Name + "." + name
+			}
+		}
+		start, end := fset.Position(fn.Pos()), fset.Position(fn.End())
+		stat := CoverageStat{Kind: "function", Name: p.FileName + ":" + name}
+		for _, b := range p.Blocks {
+			if (b.StartLine > start.Line || (b.StartLine == start.Line && b.StartCol >= start.Column)) &&
+				(b.EndLine < end.Line || (b.EndLine == end.Line && b.EndCol <= end.Column)) {
+				stat.add(b)
+			}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// findGoMod return the nearest directory of go.mod from dir upward
+func findGoMod(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("kitgo: coverage: go.mod not found")
+		}
+		dir = parent
+	}
+}
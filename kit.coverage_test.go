@@ -0,0 +1,218 @@
+package kitgo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_coverage(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+	write("go.mod", "module example.com/m\n\ngo 1.16\n")
+	write("a.go", `package m
+
+func A(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+type T struct{}
+
+func (T) B() {}
+
+func (*T) C() {}
+
+func D()
+`)
+	const profile = `mode: set
+example.com/m/a.go:3.21,4.8 1 1
+example.com/m/a.go:4.8,6.3 1 1
+example.com/m/a.go:7.2,7.10 1 0
+example.com/m/a.go:12.14,12.15 0 0
+example.com/m/a.go:14.16,14.17 0 1
+example.com/m/a.go:7.2,7.10 1 0
+example.com/m/a.go:3.21,4.8 1 0
+example.com/other/b.go:1.1,2.2 3 0
+`
+	write("coverage.out", profile)
+
+	t.Run("Parse", func(t *testing.T) {
+		profiles, err := kitgo.Coverage.Parse(strings.NewReader(profile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles).To(HaveLen(2))
+		Expect(profiles[0].FileName).To(Equal("example.com/m/a.go"))
+		Expect(profiles[0].Mode).To(Equal("set"))
+		Expect(profiles[0].Blocks).To(Equal([]kitgo.CoverageBlock{
+			{3, 21, 4, 8, 1, 1},
+			{4, 8, 6, 3, 1, 1},
+			{7, 2, 7, 10, 1, 0},
+			{12, 14, 12, 15, 0, 0},
+			{14, 16, 14, 17, 0, 1},
+		}))
+
+		profiles, err = kitgo.Coverage.Parse(strings.NewReader("mode: count\n\na.go:1.1,2.2 1 2\na.go:1.1,2.2 1 3\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles[0].Blocks[0].Count).To(Equal(5))
+		profiles, err = kitgo.Coverage.Parse(strings.NewReader("mode: set\na.go:1.1,2.2 1 0\na.go:1.1,2.2 1 1\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profiles[0].Blocks[0].Count).To(Equal(1))
+
+		for input, expect := range map[string]string{
+			"a.go:1.1,2.2 1 1":                         "kitgo: coverage profile: line 1: missing mode",
+			"mode: set\na.go 1 1":                      `kitgo: coverage profile: line 2: invalid block "a.go 1 1"`,
+			"mode: set\na.go:1.1,2.2 1":                `kitgo: coverage profile: line 2: invalid block "a.go:1.1,2.2 1"`,
+			"mode: set\na.go:1.x,2.2 1 1":              `kitgo: coverage profile: line 2: invalid block "a.go:1.x,2.2 1 1"`,
+			"mode: set\n" + strings.Repeat("x", 1<<17): "bufio.Scanner: token too long",
+		} {
+			_, err := kitgo.Coverage.Parse(strings.NewReader(input))
+			Expect(err).To(MatchError(expect))
+		}
+	})
+	t.Run("Stats", func(t *testing.T) {
+		profiles, err := kitgo.Coverage.Parse(strings.NewReader(profile))
+		Expect(err).NotTo(HaveOccurred())
+		stats, err := kitgo.Coverage.Stats(profiles, dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal([]kitgo.CoverageStat{
+			{Kind: "package", Name: "example.com/m", Covered: 2, Statements: 3},
+			{Kind: "file", Name: "example.com/m/a.go", Covered: 2, Statements: 3},
+			{Kind: "function", Name: "example.com/m/a.go:A", Covered: 2, Statements: 3},
+			{Kind: "function", Name: "example.com/m/a.go:T.B", Covered: 0, Statements: 0},
+			{Kind: "function", Name: "example.com/m/a.go:T.C", Covered: 0, Statements: 0},
+			{Kind: "package", Name: "example.com/other", Covered: 0, Statements: 3},
+			{Kind: "file", Name: "example.com/other/b.go", Covered: 0, Statements: 3},
+		}))
+		Expect(stats[3].Ratio()).To(Equal(1.0))
+
+		stats, err = kitgo.Coverage.Stats(profiles, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(HaveLen(4))
+
+		_, err = kitgo.Coverage.Stats(profiles, filepath.Join(dir, "none"))
+		Expect(err).To(HaveOccurred())
+		_, err = kitgo.Coverage.Stats([]kitgo.CoverageProfile{{FileName: "example.com/m/none.go"}}, dir)
+		Expect(err).To(HaveOccurred())
+	})
+	t.Run("Check", func(t *testing.T) {
+		baseline := filepath.Join(dir, "baseline.json")
+		out := new(bytes.Buffer)
+		conf := &kitgo.CoverageConfig{
+			Profile:  filepath.Join(dir, "coverage.out"),
+			Baseline: baseline,
+			Package:  0.5,
+			File:     0.7,
+			Function: 0.9,
+			Thresholds: map[string]float64{
+				"example.com/other/*.go": 0,
+				"example.com/*/*.go":     0.1,
+				"example.com/m/a.go:*":   0.5,
+			},
+			Root:   dir,
+			Output: out,
+		}
+		Expect(kitgo.Coverage.Check(1, conf)).To(Equal(1))
+		Expect(out.String()).To(BeEmpty())
+
+		// without baseline
+		Expect(kitgo.Coverage.Check(0, conf)).To(Equal(1))
+		Expect(out.String()).To(Equal("" +
+			"FAIL\tcoverage: 1 below the threshold\n" +
+			"\tpackage    0.0% <  50.0% (new)  example.com/other\n",
+		))
+
+		// write then compare with the baseline
+		conf.WriteBaseline = true
+		Expect(kitgo.Coverage.Evaluate(conf.Profile, conf)).To(HaveLen(1))
+		var stats []kitgo.CoverageStat
+		b, err := ioutil.ReadFile(baseline)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(b, &stats)).To(Succeed())
+		Expect(stats).To(HaveLen(7))
+
+		conf.WriteBaseline = false
+		conf.Thresholds = map[string]float64{"example.com/m/a.go:A": 1}
+		write("coverage.out", strings.Replace(profile, "3.21,4.8 1 1", "3.21,4.8 1 0", 1))
+		out.Reset()
+		Expect(kitgo.Coverage.Check(0, conf)).To(Equal(1))
+		Expect(out.String()).To(Equal("" +
+			"FAIL\tcoverage: 5 below the threshold\n" +
+			"\tpackage    0.0% <  50.0% (+0.0%)  example.com/other\n" +
+			"\tpackage   33.3% <  50.0% (-33.3%)  example.com/m\n" +
+			"\tfile       0.0% <  70.0% (+0.0%)  example.com/other/b.go\n" +
+			"\tfile      33.3% <  70.0% (-33.3%)  example.com/m/a.go\n" +
+			"\tfunction  33.3% < 100.0% (-33.3%)  example.com/m/a.go:A\n",
+		))
+	})
+	t.Run("errors", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		Expect(kitgo.Coverage.Check(0, &kitgo.CoverageConfig{Profile: filepath.Join(dir, "none.out"), Output: out})).To(Equal(1))
+		Expect(out.String()).To(HavePrefix("FAIL\tcoverage: open "))
+		Expect(kitgo.Coverage.Check(0, &kitgo.CoverageConfig{Profile: write("empty.out", "")})).To(Equal(0))
+		Expect(kitgo.Coverage.Check(1, nil)).To(Equal(1))
+
+		offenders, err := kitgo.Coverage.Evaluate(write("tie.out", "mode: set\nx/b.go:1.1,2.2 1 0\nx/a.go:1.1,2.2 1 0\n"), &kitgo.CoverageConfig{File: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(offenders).To(HaveLen(2))
+		Expect(offenders[0].Name).To(Equal("x/a.go"))
+		Expect(offenders[1].Name).To(Equal("x/b.go"))
+
+		_, err = kitgo.Coverage.Evaluate(write("invalid.out", "x"), &kitgo.CoverageConfig{})
+		Expect(err).To(MatchError("kitgo: coverage profile: line 1: missing mode"))
+		_, err = kitgo.Coverage.Evaluate(write("other.out", profile), &kitgo.CoverageConfig{Root: filepath.Join(dir, "none")})
+		Expect(err).To(HaveOccurred())
+		// the root default to the go.mod of the working directory
+		Expect(kitgo.Coverage.Evaluate(write("other.out", profile), &kitgo.CoverageConfig{Function: 1})).To(BeEmpty())
+
+		_, err = kitgo.Coverage.Evaluate(filepath.Join(dir, "coverage.out"), &kitgo.CoverageConfig{Baseline: dir})
+		Expect(err).To(HaveOccurred())
+		_, err = kitgo.Coverage.Evaluate(filepath.Join(dir, "coverage.out"), &kitgo.CoverageConfig{Baseline: write("invalid.json", "{")})
+		Expect(err.Error()).To(HavePrefix("kitgo: coverage baseline: "))
+		_, err = kitgo.Coverage.Evaluate(filepath.Join(dir, "coverage.out"), &kitgo.CoverageConfig{Baseline: filepath.Join(dir, "none", "baseline.json"), WriteBaseline: true})
+		Expect(err).To(HaveOccurred())
+	})
+}
+
+// Test_pkg_coverage_workdir is not parallel since it changes the working
+// directory, the parallel tests are paused until it returns
+func Test_pkg_coverage_workdir(t *testing.T) {
+	Expect := NewWithT(t).Expect
+
+	wd, err := os.Getwd()
+	Expect(err).NotTo(HaveOccurred())
+	defer func() { Expect(os.Chdir(wd)).To(Succeed()) }()
+
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "coverage.out")
+	Expect(ioutil.WriteFile(profile, []byte("mode: set\nx/a.go:1.1,2.2 1 0\n"), 0644)).To(Succeed())
+	conf := &kitgo.CoverageConfig{Function: 1}
+
+	// no go.mod up to the root to locate the functions
+	Expect(os.Chdir(dir)).To(Succeed())
+	_, err = kitgo.Coverage.Evaluate(profile, conf)
+	Expect(err).To(MatchError("kitgo: coverage: go.mod not found"))
+
+	// the working directory has been removed
+	gone := filepath.Join(dir, "gone")
+	Expect(os.Mkdir(gone, 0755)).To(Succeed())
+	Expect(os.Chdir(gone)).To(Succeed())
+	Expect(os.Remove(gone)).To(Succeed())
+	_, err = kitgo.Coverage.Evaluate(profile, conf)
+	Expect(err).To(HaveOccurred())
+}
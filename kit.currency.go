@@ -0,0 +1,253 @@
+package kitgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// ErrCurrencySyntax is returned when a string could not be parsed as Currency
+var ErrCurrencySyntax = errors.New("invalid currency syntax")
+
+// ParseCurrency parse a localized string such as "Rp 1.234.567,89" produced by
+// Currency.String given the same tag & format, the symbol could be replaced by
+// its ISO code, e.g. "IDR 1.234.567,89", grouping is optional but when present
+// it should follow the locale, fraction digits should not exceed the precision
+// of the currency, so that "1.234" is rejected for "ja"
+func ParseCurrency(tag, format, s string) (Currency, error) {
+	c := Currency{Tag: tag, Format: format}
+	t, err := language.Parse(tag)
+	if err != nil {
+		return c, err
+	}
+	if format == "" {
+		format = "%[1]s %[2]s"
+	}
+	loc := newCurrencyLocale(t)
+	re, err := loc.pattern(format)
+	if err != nil {
+		return c, err
+	}
+	m := re.FindStringSubmatch(strings.TrimSpace(normalizeSpace(s)))
+	if m == nil {
+		return c, currencyError(s, "does not match format %q", format)
+	}
+	c.Value, err = loc.parse(s, strings.TrimSpace(m[1]))
+	return c, err
+}
+
+// UnmarshalJSON implement json unmarshaler, Tag & Format should be set
+// beforehand to parse the localized string, a json number is taken as Value,
+// a json null leaves c unchanged
+func (c *Currency) UnmarshalJSON(b []byte) error {
+	var _ json.Unmarshaler = c
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := JSON.Unmarshal(b, &s); err != nil {
+		var f float64
+		if JSON.Unmarshal(b, &f) != nil {
+			return err
+		}
+		c.Value = f
+		return nil
+	}
+	v, err := ParseCurrency(c.Tag, c.Format, s)
+	if err == nil {
+		c.Value = v.Value
+	}
+	return err
+}
+
+// =============================================================================
+// internal
+// =============================================================================
+
+var currencyVerb = regexp.MustCompile(`%(?:\[([0-9]+)\])?s`)
+
+// currencyLocale holds the symbols of a locale, derived from the samples
+// printed by message.Printer so that it always agree with Currency.String
+type currencyLocale struct {
+	digits    map[rune]rune
+	decimal   string
+	group     string
+	minus     string
+	primary   int
+	secondary int
+	scale     int
+	symbol    string
+	code      string
+}
+
+func newCurrencyLocale(tag language.Tag) currencyLocale {
+	printer := message.NewPrinter(tag)
+	unit, _ := currency.FromTag(tag)
+	region, _ := tag.Region()
+	loc := currencyLocale{
+		digits:  map[rune]rune{},
+		decimal: stripDigits(printer.Sprintf("%.1f", 1.5)),
+		minus:   stripDigits(printer.Sprintf("%d", -1)),
+		scale:   currencyDecimals(region.String()),
+		symbol:  normalizeSpace(printer.Sprint(currency.Symbol(unit.Amount(nil)))),
+		code:    unit.String(),
+	}
+	for i := 0; i < 10; i++ {
+		for _, r := range printer.Sprintf("%d", i) {
+			if unicode.IsDigit(r) {
+				loc.digits[r] = rune('0' + i)
+			}
+		}
+	}
+	// runs of digit of 1234567 e.g. [1 3 3] or [12 34 567] for "hi"
+	var runs []int
+	var seps []string
+	for _, r := range normalizeSpace(printer.Sprintf("%d", 1234567)) {
+		if unicode.IsDigit(r) {
+			if len(runs) == len(seps) {
+				runs = append(runs, 0)
+			}
+			runs[len(runs)-1]++
+			continue
+		}
+		if len(runs) > len(seps) {
+			seps = append(seps, "")
+		}
+		seps[len(seps)-1] += string(r)
+	}
+	if len(runs) > 1 {
+		loc.group = seps[0]
+		loc.primary, loc.secondary = runs[len(runs)-1], runs[len(runs)-2]
+	}
+	return loc
+}
+
+func stripDigits(s string) string {
+	return normalizeSpace(strings.TrimFunc(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return -1
+		}
+		return r
+	}, s), unicode.IsSpace))
+}
+
+// pattern build a regexp from format, %[1]s is the symbol and %[2]s is the
+// value which is captured, spaces are optional
+func (loc currencyLocale) pattern(format string) (*regexp.Regexp, error) {
+	literal := func(s string) string {
+		return strings.ReplaceAll(regexp.QuoteMeta(normalizeSpace(s)), " ", " *")
+	}
+	b := new(strings.Builder)
+	b.WriteString("^")
+	arg, last, value := 1, 0, false
+	for _, m := range currencyVerb.FindAllStringSubmatchIndex(format, -1) {
+		b.WriteString(literal(format[last:m[0]]))
+		last = m[1]
+		if m[2] >= 0 {
+			arg, _ = strconv.Atoi(format[m[2]:m[3]])
+		}
+		switch arg {
+		case 1:
+			b.WriteString("(?:" + literal(loc.symbol) + "|" + regexp.QuoteMeta(loc.code) + ")")
+		case 2:
+			if value {
+				return nil, fmt.Errorf("kitgo: format %q: %w: value is repeated", format, ErrCurrencySyntax)
+			}
+			value = true
+			b.WriteString("(.+?)")
+		default:
+			return nil, fmt.Errorf("kitgo: format %q: %w: unknown argument %d", format, ErrCurrencySyntax, arg)
+		}
+		arg++
+	}
+	if !value {
+		return nil, fmt.Errorf("kitgo: format %q: %w: missing value", format, ErrCurrencySyntax)
+	}
+	b.WriteString(literal(format[last:]) + "$")
+	return regexp.Compile(b.String())
+}
+
+// parse the number part of s, only the locale digits, separators and minus
+// sign are accepted
+func (loc currencyLocale) parse(s, n string) (float64, error) {
+	neg := false
+	for _, minus := range []string{loc.minus, "-"} {
+		if minus != "" && strings.HasPrefix(n, minus) {
+			n, neg = strings.TrimSpace(n[len(minus):]), true
+			break
+		}
+	}
+	var integer, fraction strings.Builder
+	decimal, groups := false, []int{0}
+	for i := 0; i < len(n); {
+		switch r, size := utf8.DecodeRuneInString(n[i:]); {
+		case strings.HasPrefix(n[i:], loc.decimal):
+			if decimal {
+				return 0, currencyError(s, "multiple decimal separator %q", loc.decimal)
+			}
+			decimal = true
+			i += len(loc.decimal)
+		case loc.group != "" && strings.HasPrefix(n[i:], loc.group):
+			if decimal {
+				return 0, currencyError(s, "group separator %q after decimal separator", loc.group)
+			}
+			groups = append(groups, 0)
+			i += len(loc.group)
+		default:
+			d, ok := loc.digits[r]
+			if !ok && r >= '0' && r <= '9' {
+				d, ok = r, true
+			}
+			if !ok {
+				return 0, currencyError(s, "unexpected %q", r)
+			}
+			if decimal {
+				fraction.WriteRune(d)
+			} else {
+				integer.WriteRune(d)
+				groups[len(groups)-1]++
+			}
+			i += size
+		}
+	}
+	switch {
+	case integer.Len() < 1:
+		return 0, currencyError(s, "missing integer digits")
+	case decimal && fraction.Len() < 1:
+		return 0, currencyError(s, "missing fraction digits")
+	case fraction.Len() > loc.scale:
+		return 0, currencyError(s, "more than %d fraction digits", loc.scale)
+	}
+	if len(groups) > 1 {
+		for i, g := range groups {
+			switch {
+			case i == len(groups)-1 && g != loc.primary,
+				i > 0 && i < len(groups)-1 && g != loc.secondary,
+				i == 0 && (g < 1 || g > loc.secondary):
+				return 0, currencyError(s, "invalid grouping")
+			}
+		}
+	}
+	str := integer.String()
+	if fraction.Len() > 0 {
+		str += "." + fraction.String()
+	}
+	f, _ := strconv.ParseFloat(str, 64)
+	if neg {
+		f = -f
+	}
+	return f, nil
+}
+
+func currencyError(s, reason string, args ...interface{}) error {
+	return fmt.Errorf("kitgo: parse currency %q: %w: %s", s, ErrCurrencySyntax, fmt.Sprintf(reason, args...))
+}
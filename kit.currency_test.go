@@ -0,0 +1,81 @@
+package kitgo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_currency_parse(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	t.Run("valid", func(t *testing.T) {
+		for _, tt := range []struct {
+			tag, format, s string
+			expect         float64
+		}{
+			{"id", "", "Rp 1.234.567,89", 1234567.89},
+			{"id", "", "IDR 1.234.567,89", 1234567.89},
+			{"id", "", "  Rp1234567,8 ", 1234567.8},
+			{"id", "", "Rp -1.000", -1000},
+			{"id", "%s %s", "Rp 1.000", 1000},
+			{"id", "%[2]s (%[1]s)", "1.000 (Rp)", 1000},
+			{"en-IN", "", "₹ 12,34,567.89", 1234567.89},
+			{"fr", "%[2]s %[1]s", "1 234 567,89 €", 1234567.89},
+			{"rm", "", "CHF −1’234.5", -1234.5},
+			{"ar", "", "ج.م.‏ ؜-١٬٢٣٤٫٥", -1234.5},
+			{"ar", "", "EGP 1234", 1234},
+			{"ja", "", "￥ 1,234", 1234},
+		} {
+			c, err := kitgo.ParseCurrency(tt.tag, tt.format, tt.s)
+			Expect(err).NotTo(HaveOccurred(), tt.s)
+			Expect(c).To(Equal(kitgo.Currency{Tag: tt.tag, Value: tt.expect, Format: tt.format}), tt.s)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, tt := range []struct{ tag, format, s, expect string }{
+			{"id", "", "$ 1.000", `kitgo: parse currency "$ 1.000": invalid currency syntax: does not match format "%[1]s %[2]s"`},
+			{"id", "", "Rp 1,000,00", `kitgo: parse currency "Rp 1,000,00": invalid currency syntax: multiple decimal separator ","`},
+			{"id", "", "Rp 1,000.00", `kitgo: parse currency "Rp 1,000.00": invalid currency syntax: group separator "." after decimal separator`},
+			{"id", "", "Rp 1x", `kitgo: parse currency "Rp 1x": invalid currency syntax: unexpected 'x'`},
+			{"id", "", "Rp ,5", `kitgo: parse currency "Rp ,5": invalid currency syntax: missing integer digits`},
+			{"id", "", "Rp 1,", `kitgo: parse currency "Rp 1,": invalid currency syntax: missing fraction digits`},
+			{"id", "", "Rp 1,234", `kitgo: parse currency "Rp 1,234": invalid currency syntax: more than 2 fraction digits`},
+			{"ja", "", "￥ 1.234", `kitgo: parse currency "￥ 1.234": invalid currency syntax: more than 0 fraction digits`},
+			{"id", "", "Rp 1.23", `kitgo: parse currency "Rp 1.23": invalid currency syntax: invalid grouping`},
+			{"id", "", "Rp 1234.567", `kitgo: parse currency "Rp 1234.567": invalid currency syntax: invalid grouping`},
+			{"id", "", "Rp .234.567", `kitgo: parse currency "Rp .234.567": invalid currency syntax: invalid grouping`},
+			{"en-IN", "", "₹ 1,234,567", `kitgo: parse currency "₹ 1,234,567": invalid currency syntax: invalid grouping`},
+			{"id", "%[1]s", "Rp", `kitgo: format "%[1]s": invalid currency syntax: missing value`},
+			{"id", "%[2]s %[2]s", "1 1", `kitgo: format "%[2]s %[2]s": invalid currency syntax: value is repeated`},
+			{"id", "%[3]s", "1", `kitgo: format "%[3]s": invalid currency syntax: unknown argument 3`},
+		} {
+			_, err := kitgo.ParseCurrency(tt.tag, tt.format, tt.s)
+			Expect(errors.Is(err, kitgo.ErrCurrencySyntax)).To(BeTrue(), tt.s)
+			Expect(err.Error()).To(Equal(tt.expect))
+		}
+		_, err := kitgo.ParseCurrency("", "", "1")
+		Expect(err).To(HaveOccurred())
+	})
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		c := kitgo.Currency{Tag: "id"}
+		Expect(c.UnmarshalJSON([]byte(`"Rp 1.000,5"`))).To(Succeed())
+		Expect(c.Value).To(Equal(1000.5))
+		Expect(c.UnmarshalJSON([]byte(`12.25`))).To(Succeed())
+		Expect(c.Value).To(Equal(12.25))
+		Expect(c.UnmarshalJSON([]byte(`"Rp 1.0"`))).To(HaveOccurred())
+		Expect(c.Value).To(Equal(12.25))
+		Expect(c.UnmarshalJSON([]byte(`{}`))).To(HaveOccurred())
+		Expect(c.UnmarshalJSON([]byte(`null`))).To(Succeed())
+		Expect(c.Value).To(Equal(12.25))
+
+		v := struct {
+			Price kitgo.Currency `json:"price"`
+		}{kitgo.Currency{Tag: "en-US"}}
+		Expect(kitgo.JSON.Unmarshal([]byte(`{"price":"$ 9.99"}`), &v)).To(Succeed())
+		Expect(v.Price).To(Equal(kitgo.Currency{Tag: "en-US", Value: 9.99}))
+	})
+}
@@ -0,0 +1,547 @@
+package kitgo
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrConvert is returned when a value could not be coerced into the requested type
+var ErrConvert = errors.New("cannot convert value")
+
+// ConvertError records the path and the value that failed to be coerced,
+// it satisfy errors.Is(err, ErrConvert), Err is the underlying cause if any
+type ConvertError struct {
+	Path  string
+	Value interface{}
+	Type  string
+	Err   error
+}
+
+func (e *ConvertError) Error() string {
+	s := fmt.Sprintf("kitgo: path %q: %s %T to %s", e.Path, ErrConvert, e.Value, e.Type)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+func (e *ConvertError) Unwrap() error        { return e.Err }
+func (e *ConvertError) Is(target error) bool { return target == ErrConvert }
+
+// GetString get value given path as string, string and []byte are kept as is,
+// numbers and bool are formatted, time.Time is formatted as RFC3339Nano and
+// fmt.Stringer is used when implemented
+//
+// For all typed getters, nil (e.g. SQL NULL or json null) is the zero value
+// and a missing path returns *PathError
+func (d Dict) GetString(path string) (string, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return "", err
+	}
+	return toString(path, v)
+}
+
+// GetInt64 get value given path as int64, float should not have fraction,
+// string and []byte are parsed as base 10 and bool is either 0 or 1
+func (d Dict) GetInt64(path string) (int64, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return 0, err
+	}
+	return toInt64(path, v)
+}
+
+// GetFloat get value given path as float64, string and []byte are parsed
+// and bool is either 0 or 1
+func (d Dict) GetFloat(path string) (float64, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat(path, v)
+}
+
+// GetBool get value given path as bool, numbers are true when not zero, string
+// and []byte are parsed with strconv.ParseBool
+func (d Dict) GetBool(path string) (bool, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return false, err
+	}
+	return toBool(path, v)
+}
+
+// GetTime get value given path as time.Time, string and []byte are parsed as
+// RFC3339, "2006-01-02 15:04:05" or "2006-01-02" and numbers are unix seconds
+func (d Dict) GetTime(path string) (time.Time, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return toTime(path, v)
+}
+
+// GetDict get value given path as Dict, any map with string key is accepted
+func (d Dict) GetDict(path string) (Dict, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return toDict(path, v)
+}
+
+// GetList get value given path as List, any slice or array other than []byte
+// is accepted
+func (d Dict) GetList(path string) (List, error) {
+	v, err := d.GetPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return toList(path, v)
+}
+
+// Decode populate v, a non-nil pointer, honouring `json` tags and the same
+// coercion rules of the typed getters, nested struct, map, slice, pointer,
+// time.Duration (from string such as "5s"), encoding.TextUnmarshaler and
+// json.Unmarshaler are supported, every failure is collected as errorList
+func (d Dict) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("kitgo: decode requires a non-nil pointer, got %T", v)
+	}
+	var errs errorList
+	decodeValue(&errs, "", map[string]interface{}(d), rv.Elem())
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// =============================================================================
+// internal
+// =============================================================================
+
+var (
+	typeTime          = reflect.TypeOf(time.Time{})
+	typeDuration      = reflect.TypeOf(time.Duration(0))
+	typeTextMarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	typeJSONMarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	timeLayouts       = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+)
+
+func convertError(path string, v interface{}, typ string, err error) error {
+	return &ConvertError{path, v, typ, err}
+}
+
+func toString(path string, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.String:
+		return rv.String(), nil
+	}
+	return "", convertError(path, v, "string", nil)
+}
+
+func toInt64(path string, v interface{}) (int64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	case string, []byte, json.Number:
+		s, _ := toString(path, x)
+		s = strings.TrimSpace(s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, convertError(path, v, "int64", err)
+		}
+		return toInt64(path, f)
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, convertError(path, v, "int64", strconv.ErrRange)
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, convertError(path, v, "int64", strconv.ErrRange)
+		}
+		return int64(f), nil
+	}
+	return 0, convertError(path, v, "int64", nil)
+}
+
+func toFloat(path string, v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	case string, []byte, json.Number:
+		s, _ := toString(path, x)
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0, convertError(path, v, "float64", err)
+		}
+		return f, nil
+	}
+	if f, ok := numberJSON(v); ok {
+		return f, nil
+	}
+	return 0, convertError(path, v, "float64", nil)
+}
+
+func toBool(path string, v interface{}) (bool, error) {
+	switch x := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return x, nil
+	case string, []byte:
+		s, _ := toString(path, x)
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return false, convertError(path, v, "bool", err)
+		}
+		return b, nil
+	}
+	if f, ok := numberJSON(v); ok {
+		return f != 0, nil
+	}
+	return false, convertError(path, v, "bool", nil)
+}
+
+func toTime(path string, v interface{}) (time.Time, error) {
+	switch x := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return x, nil
+	case *time.Time:
+		if x == nil {
+			return time.Time{}, nil
+		}
+		return *x, nil
+	case string, []byte:
+		s, _ := toString(path, x)
+		s = strings.TrimSpace(s)
+		var err error
+		for _, layout := range timeLayouts {
+			var t time.Time
+			if t, err = time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, convertError(path, v, "time.Time", err)
+	}
+	if f, ok := numberJSON(v); ok {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+	}
+	return time.Time{}, convertError(path, v, "time.Time", nil)
+}
+
+func toDict(path string, v interface{}) (Dict, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case Dict:
+		return x, nil
+	case map[string]interface{}:
+		return x, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		d := make(Dict, rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			d[it.Key().String()] = it.Value().Interface()
+		}
+		return d, nil
+	}
+	return nil, convertError(path, v, "Dict", nil)
+}
+
+func toList(path string, v interface{}) (List, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case List:
+		return x, nil
+	case []interface{}:
+		return x, nil
+	case []byte:
+		return nil, convertError(path, v, "List", nil)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		l := make(List, rv.Len())
+		for i := range l {
+			l[i] = rv.Index(i).Interface()
+		}
+		return l, nil
+	}
+	return nil, convertError(path, v, "List", nil)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// decodeValue decode src into dst, error is appended into errs and the
+// decoding is continued so that every failure is reported
+func decodeValue(errs *errorList, path string, src interface{}, dst reflect.Value) {
+	fail := func(err error) { *errs = errs.Append(err) }
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+		return
+	}
+	switch typ := dst.Type(); {
+	case typ == typeTime:
+		t, err := toTime(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.Set(reflect.ValueOf(t))
+		return
+	case typ == typeDuration:
+		if s, ok := src.(string); ok {
+			t, err := time.ParseDuration(strings.TrimSpace(s))
+			if err != nil {
+				fail(convertError(path, src, typ.String(), err))
+				return
+			}
+			dst.SetInt(int64(t))
+			return
+		}
+	case dst.Kind() != reflect.Ptr && reflect.PtrTo(typ).Implements(typeJSONMarshaler):
+		b, err := JSON.Marshal(src)
+		if err == nil {
+			err = dst.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(b)
+		}
+		if err != nil {
+			fail(convertError(path, src, typ.String(), err))
+		}
+		return
+	case dst.Kind() != reflect.Ptr && reflect.PtrTo(typ).Implements(typeTextMarshaler):
+		if s, ok := src.(string); ok {
+			if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				fail(convertError(path, src, typ.String(), err))
+			}
+			return
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		decodeValue(errs, path, src, dst.Elem())
+	case reflect.Interface:
+		if v := reflect.ValueOf(src); v.Type().AssignableTo(dst.Type()) {
+			dst.Set(v)
+			return
+		}
+		fail(convertError(path, src, dst.Type().String(), nil))
+	case reflect.String:
+		s, err := toString(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		b, err := toBool(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(path, src)
+		if err == nil && dst.OverflowInt(i) {
+			err = convertError(path, src, dst.Type().String(), strconv.ErrRange)
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(path, src)
+		if err == nil && (i < 0 || dst.OverflowUint(uint64(i))) {
+			err = convertError(path, src, dst.Type().String(), strconv.ErrRange)
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(path, src)
+		if err == nil && dst.OverflowFloat(f) {
+			err = convertError(path, src, dst.Type().String(), strconv.ErrRange)
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		dst.SetFloat(f)
+	case reflect.Struct:
+		m, err := toDict(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		decodeStruct(errs, path, m, dst)
+	case reflect.Map:
+		m, err := toDict(path, src)
+		if err == nil && dst.Type().Key().Kind() != reflect.String {
+			err = convertError(path, src, dst.Type().String(), nil)
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+		}
+		for k, v := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			decodeValue(errs, joinPath(path, k), v, elem)
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := src.(string); ok {
+				dst.SetBytes([]byte(s))
+				return
+			} else if b, ok := src.([]byte); ok {
+				dst.SetBytes(append([]byte(nil), b...))
+				return
+			}
+		}
+		l, err := toList(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		s := reflect.MakeSlice(dst.Type(), len(l), len(l))
+		for i := range l {
+			decodeValue(errs, fmt.Sprintf("%s[%d]", path, i), l[i], s.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Array:
+		l, err := toList(path, src)
+		if err != nil {
+			fail(err)
+			return
+		}
+		for i := 0; i < len(l) && i < dst.Len(); i++ {
+			decodeValue(errs, fmt.Sprintf("%s[%d]", path, i), l[i], dst.Index(i))
+		}
+	default:
+		fail(convertError(path, src, dst.Type().String(), nil))
+	}
+}
+
+// decodeStruct decode each exported field by its `json` tag, falling back to
+// a case-insensitive match of the key, the least one in byte order when several
+// keys match, embedded struct fields are promoted
+func decodeStruct(errs *errorList, path string, m Dict, dst reflect.Value) {
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, tagged := f.Name, false
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name, tagged = n, true
+			}
+		}
+		if f.Anonymous && !tagged {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fv := dst.Field(i)
+				if fv.Kind() == reflect.Ptr {
+					if !fv.CanSet() {
+						continue
+					}
+					if fv.IsNil() {
+						fv.Set(reflect.New(ft))
+					}
+					fv = fv.Elem()
+				}
+				decodeStruct(errs, path, m, fv)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		v, ok := m[name]
+		if !ok {
+			var key string
+			for k := range m {
+				if strings.EqualFold(k, name) && (!ok || k < key) {
+					key, ok = k, true
+				}
+			}
+			v = m[key]
+		}
+		if ok {
+			decodeValue(errs, joinPath(path, name), v, dst.Field(i))
+		}
+	}
+}
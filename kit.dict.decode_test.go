@@ -0,0 +1,306 @@
+package kitgo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+type decodeUpper string
+
+func (u *decodeUpper) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	*u = decodeUpper(strings.ToUpper(s))
+	return err
+}
+
+func Test_pkg_dict_decode(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := kitgo.Dict{
+		"nil":    nil,
+		"str":    "hello",
+		"bytes":  []byte(" 42 "),
+		"int":    int64(7),
+		"uint":   uint8(8),
+		"huge":   uint64(1 << 63),
+		"float":  1.5,
+		"whole":  float64(3),
+		"f32":    float32(0.25),
+		"bool":   true,
+		"yes":    "true",
+		"time":   now,
+		"ptime":  &now,
+		"rfc":    "2021-01-02T03:04:05Z",
+		"sql":    []byte("2021-01-02 03:04:05"),
+		"date":   "2021-01-02",
+		"unix":   1609556645.5,
+		"number": json.Number("12"),
+		"ip":     net.IPv4(127, 0, 0, 1),
+		"dict":   map[string]interface{}{"a": 1},
+		"row":    kitgo.SQLResultQueryRow{"a": 2},
+		"list":   []interface{}{1, 2},
+		"ints":   []int{1, 2},
+		"struct": struct{}{},
+		"named":  decodeUpper("up"),
+	}
+
+	t.Run("GetString", func(t *testing.T) {
+		for path, expect := range map[string]string{
+			"nil": "", "str": "hello", "bytes": " 42 ", "int": "7", "uint": "8",
+			"float": "1.5", "f32": "0.25", "bool": "true", "time": "2021-01-02T03:04:05Z",
+			"number": "12", "ip": "127.0.0.1", "named": "up",
+		} {
+			Expect(d.GetString(path)).To(Equal(expect), path)
+		}
+		_, err := d.GetString("dict")
+		Expect(errors.Is(err, kitgo.ErrConvert)).To(BeTrue())
+		_, err = d.GetString("none")
+		Expect(errors.Is(err, kitgo.ErrPathNotFound)).To(BeTrue())
+	})
+	t.Run("GetInt64", func(t *testing.T) {
+		for path, expect := range map[string]int64{
+			"nil": 0, "bytes": 42, "int": 7, "uint": 8, "whole": 3, "bool": 1, "number": 12,
+		} {
+			Expect(d.GetInt64(path)).To(Equal(expect), path)
+		}
+		Expect(kitgo.Dict{"s": "1e3", "f": false}.GetInt64("s")).To(Equal(int64(1000)))
+		Expect(kitgo.Dict{"s": "1e3", "f": false}.GetInt64("f")).To(Equal(int64(0)))
+		for _, path := range []string{"str", "huge", "float", "dict", "none"} {
+			_, err := d.GetInt64(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+		_, err := d.GetInt64("str")
+		ce := new(kitgo.ConvertError)
+		Expect(errors.As(err, &ce)).To(BeTrue())
+		Expect(ce.Path).To(Equal("str"))
+		Expect(ce.Value).To(Equal("hello"))
+		Expect(ce.Type).To(Equal("int64"))
+		Expect(errors.Unwrap(err)).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix(`kitgo: path "str": cannot convert value string to int64: strconv.ParseFloat`))
+	})
+	t.Run("GetFloat", func(t *testing.T) {
+		for path, expect := range map[string]float64{
+			"nil": 0, "bytes": 42, "int": 7, "float": 1.5, "bool": 1, "number": 12,
+		} {
+			Expect(d.GetFloat(path)).To(Equal(expect), path)
+		}
+		Expect(kitgo.Dict{"f": false}.GetFloat("f")).To(Equal(float64(0)))
+		for _, path := range []string{"str", "dict", "none"} {
+			_, err := d.GetFloat(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+	})
+	t.Run("GetBool", func(t *testing.T) {
+		for path, expect := range map[string]bool{
+			"nil": false, "bool": true, "yes": true, "int": true, "whole": true,
+		} {
+			Expect(d.GetBool(path)).To(Equal(expect), path)
+		}
+		for _, path := range []string{"str", "dict", "none"} {
+			_, err := d.GetBool(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+	})
+	t.Run("GetTime", func(t *testing.T) {
+		for _, path := range []string{"time", "ptime", "rfc", "sql"} {
+			Expect(d.GetTime(path)).To(Equal(now), path)
+		}
+		Expect(d.GetTime("date")).To(Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)))
+		Expect(d.GetTime("nil")).To(Equal(time.Time{}))
+		Expect(kitgo.Dict{"t": (*time.Time)(nil)}.GetTime("t")).To(Equal(time.Time{}))
+		unix, err := d.GetTime("unix")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unix.UTC()).To(Equal(now.Add(500 * time.Millisecond)))
+		for _, path := range []string{"str", "dict", "none"} {
+			_, err := d.GetTime(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+	})
+	t.Run("GetDict/GetList", func(t *testing.T) {
+		Expect(d.GetDict("dict")).To(Equal(kitgo.Dict{"a": 1}))
+		Expect(d.GetDict("row")).To(Equal(kitgo.Dict{"a": 2}))
+		Expect(kitgo.Dict{"d": kitgo.Dict{}}.GetDict("d")).To(Equal(kitgo.Dict{}))
+		Expect(d.GetDict("nil")).To(BeNil())
+		Expect(d.GetList("list")).To(Equal(kitgo.List{1, 2}))
+		Expect(d.GetList("ints")).To(Equal(kitgo.List{1, 2}))
+		Expect(kitgo.Dict{"l": kitgo.List{}}.GetList("l")).To(Equal(kitgo.List{}))
+		Expect(d.GetList("nil")).To(BeNil())
+		for _, path := range []string{"str", "none"} {
+			_, err := d.GetDict(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+		for _, path := range []string{"bytes", "str", "none"} {
+			_, err := d.GetList(path)
+			Expect(err).To(HaveOccurred(), path)
+		}
+	})
+	t.Run("Decode", func(t *testing.T) {
+		type Base struct {
+			ID int `json:"id"`
+		}
+		type Item struct {
+			Name  string  `json:"name"`
+			Price float32 `json:"price"`
+		}
+		type Order struct {
+			Base
+			*Item    `json:"item"`
+			Meta     struct{ Note string }
+			Code     string            `json:"code"`
+			Active   bool              `json:"active"`
+			Count    uint16            `json:"count"`
+			Small    int8              `json:"small"`
+			At       time.Time         `json:"at"`
+			TTL      time.Duration     `json:"ttl"`
+			Wait     time.Duration     `json:"wait"`
+			IP       net.IP            `json:"ip"`
+			Upper    decodeUpper       `json:"upper"`
+			Items    []Item            `json:"items"`
+			Tags     [2]string         `json:"tags"`
+			Labels   map[string]*int   `json:"labels"`
+			Any      interface{}       `json:"any"`
+			Raw      []byte            `json:"raw"`
+			Blob     []byte            `json:"blob"`
+			Ptr      *string           `json:"ptr"`
+			Ignored  string            `json:"-"`
+			Default  string            `json:"default"`
+			Extra    map[string]string `json:",omitempty"`
+			internal string
+		}
+		src := kitgo.Dict{}
+		Expect(kitgo.JSON.Unmarshal([]byte(`{
+			"id": "10",
+			"item": {"name": "pen", "price": "1.5"},
+			"META": {"note": "fragile"},
+			"code": 123,
+			"active": 1,
+			"count": "3",
+			"small": 1,
+			"at": "2021-01-02T03:04:05Z",
+			"ttl": "1m",
+			"wait": 1000,
+			"ip": "127.0.0.1",
+			"upper": "abc",
+			"items": [{"name": "a", "price": 1}, {"name": "b"}],
+			"tags": ["x", "y", "z"],
+			"labels": {"a": 1, "b": null},
+			"any": [1],
+			"raw": "raw",
+			"ptr": "p",
+			"Ignored": "x",
+			"default": null,
+			"extra": {"k": "v"},
+			"internal": "x"
+		}`), &src)).To(Succeed())
+		src["blob"] = []byte("blob")
+
+		o := Order{Default: "keep"}
+		Expect(src.Decode(&o)).To(Succeed())
+		Expect(o.ID).To(Equal(10))
+		Expect(o.Item).To(Equal(&Item{"pen", 1.5}))
+		Expect(o.Meta.Note).To(Equal("fragile"))
+		Expect(o.Code).To(Equal("123"))
+		Expect(o.Active).To(BeTrue())
+		Expect(o.Count).To(Equal(uint16(3)))
+		Expect(o.Small).To(Equal(int8(1)))
+		Expect(o.At).To(Equal(now))
+		Expect(o.TTL).To(Equal(time.Minute))
+		Expect(o.Wait).To(Equal(time.Microsecond))
+		Expect(o.IP.String()).To(Equal("127.0.0.1"))
+		Expect(o.Upper).To(Equal(decodeUpper("ABC")))
+		Expect(o.Items).To(Equal([]Item{{"a", 1}, {"b", 0}}))
+		Expect(o.Tags).To(Equal([2]string{"x", "y"}))
+		Expect(o.Labels).To(HaveLen(2))
+		Expect(*o.Labels["a"]).To(Equal(1))
+		Expect(o.Labels["b"]).To(BeNil())
+		Expect(o.Any).To(Equal([]interface{}{float64(1)}))
+		Expect(string(o.Raw)).To(Equal("raw"))
+		Expect(string(o.Blob)).To(Equal("blob"))
+		Expect(*o.Ptr).To(Equal("p"))
+		Expect(o.Ignored).To(BeEmpty())
+		Expect(o.Default).To(Equal("keep"))
+		Expect(o.Extra).To(Equal(map[string]string{"k": "v"}))
+		Expect(o.internal).To(BeEmpty())
+
+		type embedded struct{ E int }
+		var e struct {
+			*Base
+			*embedded
+		}
+		Expect(kitgo.Dict{"id": 1, "e": 2}.Decode(&e)).To(Succeed())
+		Expect(e.Base).To(Equal(&Base{1}))
+		Expect(e.embedded).To(BeNil())
+
+		// the exact key first, else the least of the case-insensitive keys
+		var named struct{ Name, Code int }
+		for i := 0; i < 10; i++ {
+			Expect(kitgo.Dict{"name": 1, "Name": 2, "NAME": 3, "code": 4, "CODE": 5, "Code_": 6}.Decode(&named)).To(Succeed())
+			Expect(named.Name).To(Equal(2))
+			Expect(named.Code).To(Equal(5))
+		}
+
+		var m map[string]interface{}
+		Expect(kitgo.Dict{"a": 1}.Decode(&m)).To(Succeed())
+		Expect(m).To(Equal(map[string]interface{}{"a": 1}))
+
+		Expect(kitgo.Dict{}.Decode(nil)).To(HaveOccurred())
+		Expect(kitgo.Dict{}.Decode(o)).To(HaveOccurred())
+	})
+	t.Run("Decode errorList", func(t *testing.T) {
+		var o struct {
+			A int               `json:"a"`
+			B uint              `json:"b"`
+			C float32           `json:"c"`
+			D bool              `json:"d"`
+			E time.Time         `json:"e"`
+			F time.Duration     `json:"f"`
+			G []int             `json:"g"`
+			H [1]int            `json:"h"`
+			I struct{ J int }   `json:"i"`
+			K map[int]string    `json:"k"`
+			L map[string]string `json:"l"`
+			M net.IP            `json:"m"`
+			N decodeUpper       `json:"n"`
+			O fmtStringer       `json:"o"`
+			P chan int          `json:"p"`
+			Q string            `json:"q"`
+			R int8              `json:"r"`
+		}
+		err := kitgo.Dict{
+			"a": "x", "b": -1, "c": 1e300, "d": "x", "e": "x", "f": "x",
+			"g": []interface{}{"x", 1}, "h": "x", "i": kitgo.Dict{"J": "x"}, "k": kitgo.Dict{},
+			"l": kitgo.Dict{"x": kitgo.Dict{}}, "m": "x", "n": 1, "o": 1, "p": 1, "q": kitgo.List{}, "r": 300,
+		}.Decode(&o)
+		Expect(err).To(HaveOccurred())
+		list := []string{}
+		b, _ := json.Marshal(err)
+		Expect(json.Unmarshal(b, &list)).To(Succeed())
+		Expect(list).To(HaveLen(17))
+		Expect(list).To(ContainElement(`kitgo: path "g[0]": cannot convert value string to int64: strconv.ParseFloat: parsing "x": invalid syntax`))
+		Expect(list).To(ContainElement(`kitgo: path "i.J": cannot convert value string to int64: strconv.ParseFloat: parsing "x": invalid syntax`))
+		Expect(list).To(ContainElement(`kitgo: path "l.x": cannot convert value kitgo.Dict to string`))
+		Expect(list).To(ContainElement(`kitgo: path "r": cannot convert value int to int8: value out of range`))
+		Expect(o.G).To(Equal([]int{0, 1}))
+
+		var s struct{ A []int }
+		Expect(kitgo.Dict{"a": kitgo.Dict{}}.Decode(&s)).To(HaveOccurred())
+		var a struct{ A [1]int }
+		Expect(kitgo.Dict{"a": 1}.Decode(&a)).To(HaveOccurred())
+		var st struct{ A struct{} }
+		Expect(kitgo.Dict{"a": 1}.Decode(&st)).To(HaveOccurred())
+		var mp struct{ A map[string]int }
+		Expect(kitgo.Dict{"a": 1}.Decode(&mp)).To(HaveOccurred())
+	})
+}
+
+type fmtStringer interface{ String() string }
@@ -0,0 +1,404 @@
+package kitgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPatchOperation is returned when an operation is unknown or malformed
+	ErrPatchOperation = errors.New("invalid patch operation")
+	// ErrPatchTest is returned when a "test" operation is not satisfied
+	ErrPatchTest = errors.New("patch test failed")
+)
+
+// JSONPatch is a list of operation according to JSON Patch (RFC 6902)
+type JSONPatch []JSONPatchOperation
+
+// JSONPatchOperation is a single operation of JSONPatch, Op is one of "add",
+// "remove", "replace", "move", "copy" or "test", Path and From are JSON Pointer
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON implement json marshaler, value is kept for "add", "replace"
+// and "test" even if it is null
+func (o JSONPatchOperation) MarshalJSON() ([]byte, error) {
+	var _ json.Marshaler = o
+	v := struct {
+		Op    string       `json:"op"`
+		From  *string      `json:"from,omitempty"`
+		Path  string       `json:"path"`
+		Value *interface{} `json:"value,omitempty"`
+	}{Op: o.Op, Path: o.Path}
+	switch o.Op {
+	case "add", "replace", "test":
+		v.Value = &o.Value
+	case "move", "copy":
+		v.From = &o.From
+	}
+	return JSON.Marshal(v)
+}
+
+// ApplyPatch apply JSON Patch (RFC 6902), the patch is applied atomically, if
+// any operation fails, Dict is untouched and the failure is returned as
+// errorList, the following operations are not applied
+func (d *Dict) ApplyPatch(patch JSONPatch) error {
+	d.do(nil)
+	doc, err := patch.apply(copyJSON(map[string]interface{}(*d)))
+	if err != nil {
+		return err
+	}
+	if m, ok := toMap(doc); ok {
+		*d = m
+		return nil
+	}
+	return NewErrors(fmt.Errorf("kitgo: patch result is not an object: %w", ErrPatchOperation))
+}
+
+// ApplyPatch apply JSON Patch (RFC 6902), see Dict.ApplyPatch
+func (l *List) ApplyPatch(patch JSONPatch) error {
+	l.do(nil)
+	doc, err := patch.apply(copyJSON([]interface{}(*l)))
+	if err != nil {
+		return err
+	}
+	if s, ok := toSlice(doc); ok {
+		*l = s
+		return nil
+	}
+	return NewErrors(fmt.Errorf("kitgo: patch result is not an array: %w", ErrPatchOperation))
+}
+
+// ApplyMergePatch apply JSON Merge Patch (RFC 7396), patch could be a Dict,
+// map[string]interface{} or its json encoded []byte, the failure is returned
+// as errorList
+func (d *Dict) ApplyMergePatch(patch interface{}) error {
+	patch, err := decodePatch(patch)
+	if err != nil {
+		return NewErrors(err)
+	}
+	if _, ok := toMap(patch); !ok {
+		return NewErrors(fmt.Errorf("kitgo: merge patch is not an object: %w", ErrPatchOperation))
+	}
+	m, _ := toMap(mergePatch(copyJSON(map[string]interface{}(*d)), patch))
+	*d = m
+	return nil
+}
+
+// ApplyMergePatch apply JSON Merge Patch (RFC 7396), since an array is never
+// merged the List is replaced by patch, which should be an array
+func (l *List) ApplyMergePatch(patch interface{}) error {
+	patch, err := decodePatch(patch)
+	if err != nil {
+		return NewErrors(err)
+	}
+	s, ok := toSlice(patch)
+	if !ok {
+		return NewErrors(fmt.Errorf("kitgo: merge patch is not an array: %w", ErrPatchOperation))
+	}
+	*l = copyJSON(s).(List)
+	return nil
+}
+
+// NewJSONPatch generate JSON Patch that will transform from into to, the
+// operations are ordered by key so that the result is deterministic
+func NewJSONPatch(from, to interface{}) JSONPatch {
+	return diffJSON(nil, "", from, to)
+}
+
+// =============================================================================
+// internal
+// =============================================================================
+
+// apply stop at the first failing operation, the next ones would only report
+// failures caused by the missing change
+func (patch JSONPatch) apply(doc interface{}) (interface{}, error) {
+	for i, o := range patch {
+		next, err := o.apply(doc)
+		if err != nil {
+			return nil, NewErrors(fmt.Errorf("kitgo: patch %d %q %q: %w", i, o.Op, o.Path, err))
+		}
+		doc = next
+	}
+	return doc, nil
+}
+
+func (o JSONPatchOperation) apply(doc interface{}) (interface{}, error) {
+	if o.Path != "" && o.Path[0] != '/' {
+		return nil, &PathError{o.Path, -1, "", ErrPathSyntax}
+	}
+	p, err := parsePath(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "add":
+		return p.add(doc, copyJSON(o.Value))
+	case "remove":
+		return p.update(doc, false, p.remove)
+	case "replace":
+		if len(p.segs) < 1 {
+			return copyJSON(o.Value), nil
+		}
+		return p.update(doc, false, func(node interface{}, i int) (interface{}, error) {
+			if _, err := p.child(node, i); err != nil {
+				return nil, err
+			}
+			return p.put(node, i, copyJSON(o.Value))
+		})
+	case "move", "copy":
+		if o.From != "" && o.From[0] != '/' {
+			return nil, &PathError{o.From, -1, "", ErrPathSyntax}
+		}
+		from, err := parsePath(o.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := from.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "copy" {
+			return p.add(doc, copyJSON(v))
+		}
+		if o.From == o.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(o.Path, o.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move %q into its child", ErrPatchOperation, o.From)
+		}
+		// work on a copy, so that a failing add does not leave doc half moved,
+		// removing could not fail since the value has been resolved
+		doc, _ = from.update(copyJSON(doc), false, from.remove)
+		return p.add(doc, v)
+	case "test":
+		v, err := p.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(v, o.Value) {
+			return nil, ErrPatchTest
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrPatchOperation, o.Op)
+}
+
+// add replace the whole doc on empty path, set a member of an object or
+// insert an element of an array by shifting the rest of the elements
+func (p jsonPath) add(doc, v interface{}) (interface{}, error) {
+	if len(p.segs) < 1 {
+		return v, nil
+	}
+	return p.update(doc, false, func(node interface{}, i int) (interface{}, error) {
+		if s, ok := toSlice(node); ok {
+			k, err := p.index(i, len(s))
+			if err != nil {
+				return nil, err
+			}
+			if k > len(s) {
+				return nil, p.err(i, ErrPathIndex)
+			}
+			l := make(List, 0, len(s)+1)
+			l = append(append(append(l, s[:k]...), v), s[k:]...)
+			if _, ok := node.(List); ok {
+				return l, nil
+			}
+			return []interface{}(l), nil
+		}
+		return p.put(node, i, v)
+	})
+}
+
+func decodePatch(patch interface{}) (interface{}, error) {
+	switch b := patch.(type) {
+	case []byte:
+		var v interface{}
+		err := JSON.Unmarshal(b, &v)
+		return v, err
+	case json.RawMessage:
+		return decodePatch([]byte(b))
+	}
+	return patch, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := toMap(patch)
+	if !ok {
+		return copyJSON(patch)
+	}
+	t, ok := toMap(target)
+	if !ok {
+		t = Dict{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	if _, ok := target.(map[string]interface{}); ok {
+		return map[string]interface{}(t)
+	}
+	return t
+}
+
+func diffJSON(patch JSONPatch, path string, from, to interface{}) JSONPatch {
+	if equalJSON(from, to) {
+		return patch
+	}
+	fm, okFrom := toMap(from)
+	tm, okTo := toMap(to)
+	if okFrom && okTo {
+		keys := make([]string, 0, len(fm)+len(tm))
+		for k := range fm {
+			keys = append(keys, k)
+		}
+		for k := range tm {
+			if _, ok := fm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1")
+			f, inFrom := fm[k]
+			t, inTo := tm[k]
+			switch {
+			case !inTo:
+				patch = append(patch, JSONPatchOperation{Op: "remove", Path: p})
+			case !inFrom:
+				patch = append(patch, JSONPatchOperation{Op: "add", Path: p, Value: copyJSON(t)})
+			default:
+				patch = diffJSON(patch, p, f, t)
+			}
+		}
+		return patch
+	}
+	fs, okFrom := toSlice(from)
+	ts, okTo := toSlice(to)
+	if okFrom && okTo {
+		i := 0
+		for ; i < len(fs) && i < len(ts); i++ {
+			patch = diffJSON(patch, path+"/"+strconv.Itoa(i), fs[i], ts[i])
+		}
+		for j := len(fs) - 1; j >= i; j-- {
+			patch = append(patch, JSONPatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(j)})
+		}
+		for ; i < len(ts); i++ {
+			patch = append(patch, JSONPatchOperation{Op: "add", Path: path + "/-", Value: copyJSON(ts[i])})
+		}
+		return patch
+	}
+	return append(patch, JSONPatchOperation{Op: "replace", Path: path, Value: copyJSON(to)})
+}
+
+// toMap convert json-like object into Dict, the underlying map is shared
+func toMap(v interface{}) (Dict, bool) {
+	switch m := v.(type) {
+	case Dict:
+		return m, m != nil
+	case map[string]interface{}:
+		return m, m != nil
+	}
+	return nil, false
+}
+
+// toSlice convert json-like array into List, the underlying array is shared
+func toSlice(v interface{}) (List, bool) {
+	switch s := v.(type) {
+	case List:
+		return s, s != nil
+	case []interface{}:
+		return s, s != nil
+	}
+	return nil, false
+}
+
+// copyJSON deep copy json-like value, the type of each container is kept
+func copyJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case Dict:
+		return Dict(copyJSON(map[string]interface{}(x)).(map[string]interface{}))
+	case map[string]interface{}:
+		if x == nil {
+			return x
+		}
+		m := make(map[string]interface{}, len(x))
+		for k := range x {
+			m[k] = copyJSON(x[k])
+		}
+		return m
+	case List:
+		return List(copyJSON([]interface{}(x)).([]interface{}))
+	case []interface{}:
+		if x == nil {
+			return x
+		}
+		s := make([]interface{}, len(x))
+		for i := range x {
+			s[i] = copyJSON(x[i])
+		}
+		return s
+	}
+	return v
+}
+
+// equalJSON compare json-like value, numbers are compared by its value
+func equalJSON(a, b interface{}) bool {
+	if am, ok := toMap(a); ok {
+		bm, ok := toMap(b)
+		if !ok || len(am) != len(bm) {
+			return false
+		}
+		for k := range am {
+			if v, ok := bm[k]; !ok || !equalJSON(am[k], v) {
+				return false
+			}
+		}
+		return true
+	}
+	if as, ok := toSlice(a); ok {
+		bs, ok := toSlice(b)
+		if !ok || len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if !equalJSON(as[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if an, ok := numberJSON(a); ok {
+		bn, ok := numberJSON(b)
+		return ok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func numberJSON(v interface{}) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,178 @@
+package kitgo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_dict_patch(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	dict := func(s string) kitgo.Dict {
+		d := kitgo.Dict{}
+		Expect(kitgo.JSON.Unmarshal([]byte(s), &d)).To(Succeed())
+		return d
+	}
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		Expect(err).NotTo(HaveOccurred())
+		return string(b)
+	}
+	patch := func(s string) kitgo.JSONPatch {
+		p := kitgo.JSONPatch{}
+		Expect(kitgo.JSON.Unmarshal([]byte(s), &p)).To(Succeed())
+		return p
+	}
+
+	t.Run("rfc6902", func(t *testing.T) {
+		for _, tt := range []struct{ doc, patch, expect string }{
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+			{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+			{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+			{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+			{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+			{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+			{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+			{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+			{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"move","from":"/foo","path":"/foo"}]`, `{"foo":"bar","baz":"bar"}`},
+			{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+			{`{"foo":"bar"}`, `[{"op":"add","path":"","value":{}}]`, `{}`},
+		} {
+			d := dict(tt.doc)
+			Expect(d.ApplyPatch(patch(tt.patch))).To(Succeed(), tt.patch)
+			Expect(d).To(Equal(dict(tt.expect)), tt.patch)
+			Expect(kitgo.NewJSONPatch(dict(tt.doc), d)).To(Equal(kitgo.NewJSONPatch(dict(tt.doc), dict(tt.expect))))
+		}
+	})
+	t.Run("errorList", func(t *testing.T) {
+		d := dict(`{"foo":"bar","arr":[1,2]}`)
+		err := d.ApplyPatch(patch(`[
+			{"op":"add","path":"/ok","value":1},
+			{"op":"test","path":"/foo","value":"baz"},
+			{"op":"remove","path":"/ok"}
+		]`))
+		Expect(err).To(MatchError(`kitgo: patch 1 "test" "/foo": patch test failed`))
+		Expect(errors.Is(err, kitgo.ErrPatchTest)).To(BeTrue())
+		// untouched on failure
+		Expect(d).To(Equal(dict(`{"foo":"bar","arr":[1,2]}`)))
+
+		// the first failure stops the patch, the next operation would fail too
+		for _, op := range []string{
+			`{"op":"add","path":"/a/b","value":1}`,
+			`{"op":"remove","path":"/none"}`,
+			`{"op":"replace","path":"/none","value":1}`,
+			`{"op":"add","path":"/arr/5","value":1}`,
+			`{"op":"add","path":"/arr/x","value":1}`,
+			`{"op":"move","from":"/foo","path":"/foo/bar"}`,
+			`{"op":"move","from":"/none","path":"/bar"}`,
+			`{"op":"move","from":"/foo","path":"/arr/9"}`,
+			`{"op":"copy","from":"foo","path":"/bar"}`,
+			`{"op":"copy","from":"/~","path":"/bar"}`,
+			`{"op":"test","path":"/none","value":1}`,
+			`{"op":"unknown","path":"/foo"}`,
+			`{"op":"add","path":"foo","value":1}`,
+			`{"op":"add","path":"/~","value":1}`,
+		} {
+			err := d.ApplyPatch(patch(`[{"op":"add","path":"/ok","value":1},` + op + `,{"op":"remove","path":"/none"}]`))
+			Expect(err).To(MatchError(HavePrefix(`kitgo: patch 1 `)), op)
+			b, _ := json.Marshal(err)
+			list := []string{}
+			Expect(json.Unmarshal(b, &list)).To(Succeed())
+			Expect(list).To(HaveLen(1), op)
+			Expect(d).To(Equal(dict(`{"foo":"bar","arr":[1,2]}`)), op)
+		}
+
+		Expect(d.ApplyPatch(patch(`[{"op":"replace","path":"","value":1}]`))).To(HaveOccurred())
+		l := kitgo.List{}
+		Expect(l.ApplyPatch(patch(`[{"op":"replace","path":"","value":1}]`))).To(HaveOccurred())
+	})
+	t.Run("list", func(t *testing.T) {
+		var l kitgo.List
+		Expect(l.ApplyPatch(kitgo.JSONPatch{
+			{Op: "add", Path: "/-", Value: kitgo.List{1}},
+			{Op: "add", Path: "/0/0", Value: 0},
+			{Op: "add", Path: "/0", Value: nil},
+			{Op: "test", Path: "/0", Value: nil},
+		})).To(Succeed())
+		Expect(l).To(Equal(kitgo.List{nil, kitgo.List{0, 1}}))
+		Expect(l.ApplyPatch(kitgo.JSONPatch{{Op: "remove", Path: "/2"}})).To(HaveOccurred())
+
+		l = kitgo.List{[]interface{}(nil)}
+		Expect(l.ApplyPatch(kitgo.JSONPatch{{Op: "copy", From: "/0", Path: "/-"}})).To(Succeed())
+		Expect(l).To(Equal(kitgo.List{[]interface{}(nil), []interface{}(nil)}))
+	})
+	t.Run("marshal", func(t *testing.T) {
+		b, err := kitgo.JSON.Marshal(kitgo.JSONPatch{
+			{Op: "add", Path: "/a", Value: nil},
+			{Op: "remove", Path: "/a", Value: 1},
+			{Op: "copy", Path: "/a", From: "/b"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`[{"op":"add","path":"/a","value":null},{"op":"remove","path":"/a"},{"op":"copy","from":"/b","path":"/a"}]`))
+	})
+	t.Run("diff", func(t *testing.T) {
+		from := dict(`{"a":1,"b":{"c":[1,2,3],"d":"x"},"e/f":true,"g":[1]}`)
+		to := dict(`{"a":1.0,"b":{"c":[1,4],"d":"y"},"e/f":false,"g":[1,{"h":null}],"i":"j"}`)
+		p := kitgo.NewJSONPatch(from, to)
+		Expect(p).To(Equal(kitgo.JSONPatch{
+			{Op: "replace", Path: "/b/c/1", Value: float64(4)},
+			{Op: "remove", Path: "/b/c/2"},
+			{Op: "replace", Path: "/b/d", Value: "y"},
+			{Op: "replace", Path: "/e~1f", Value: false},
+			{Op: "add", Path: "/g/-", Value: map[string]interface{}{"h": nil}},
+			{Op: "add", Path: "/i", Value: "j"},
+		}))
+		Expect(from.ApplyPatch(p)).To(Succeed())
+		Expect(kitgo.NewJSONPatch(from, to)).To(BeEmpty())
+		Expect(kitgo.NewJSONPatch(from, kitgo.Dict{})).To(HaveLen(5))
+		Expect(kitgo.NewJSONPatch(1, "1")).To(Equal(kitgo.JSONPatch{{Op: "replace", Value: "1"}}))
+		Expect(kitgo.NewJSONPatch(kitgo.List{1}, kitgo.Dict{})).To(HaveLen(1))
+		Expect(kitgo.NewJSONPatch(kitgo.List{1}, kitgo.List{})).To(HaveLen(1))
+		Expect(kitgo.NewJSONPatch(kitgo.List{}, kitgo.List{"a"})).To(HaveLen(1))
+		Expect(kitgo.NewJSONPatch(json.Number("1"), 1)).To(BeEmpty())
+		Expect(kitgo.NewJSONPatch(json.Number("x"), 1)).To(HaveLen(1))
+		Expect(kitgo.NewJSONPatch(uint(1), int8(1))).To(BeEmpty())
+	})
+	t.Run("rfc7396", func(t *testing.T) {
+		for _, tt := range []struct{ doc, patch, expect string }{
+			{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+			{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+			{`{"a":"b"}`, `{"a":null}`, `{}`},
+			{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+			{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+			{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+			{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+			{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+			{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+			{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		} {
+			d := dict(tt.doc)
+			Expect(d.ApplyMergePatch([]byte(tt.patch))).To(Succeed(), tt.patch)
+			Expect(encode(d)).To(Equal(encode(dict(tt.expect))), tt.patch)
+		}
+		var d kitgo.Dict
+		Expect(d.ApplyMergePatch(kitgo.Dict{"a": kitgo.Dict{"b": 1}})).To(Succeed())
+		Expect(d).To(Equal(kitgo.Dict{"a": kitgo.Dict{"b": 1}}))
+		err := d.ApplyMergePatch(json.RawMessage(`["a"]`))
+		Expect(errors.Is(err, kitgo.ErrPatchOperation)).To(BeTrue())
+		b, _ := json.Marshal(err)
+		Expect(string(b)).To(Equal(`["kitgo: merge patch is not an object: invalid patch operation"]`))
+		Expect(d.ApplyMergePatch([]byte(`{`))).To(HaveOccurred())
+
+		l := kitgo.List{1}
+		Expect(l.ApplyMergePatch(kitgo.List{kitgo.Dict{"a": 1}})).To(Succeed())
+		Expect(l).To(Equal(kitgo.List{kitgo.Dict{"a": 1}}))
+		err = l.ApplyMergePatch([]byte(`{}`))
+		Expect(errors.Is(err, kitgo.ErrPatchOperation)).To(BeTrue())
+		b, _ = json.Marshal(err)
+		Expect(string(b)).To(Equal(`["kitgo: merge patch is not an array: invalid patch operation"]`))
+		Expect(l.ApplyMergePatch([]byte(`[`))).To(HaveOccurred())
+	})
+}
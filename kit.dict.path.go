@@ -0,0 +1,342 @@
+package kitgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathSyntax is returned when a path could not be parsed
+	ErrPathSyntax = errors.New("invalid path syntax")
+	// ErrPathNotFound is returned when a segment does not exist
+	ErrPathNotFound = errors.New("path not found")
+	// ErrPathIndex is returned when a segment is not a valid index of a List
+	ErrPathIndex = errors.New("invalid path index")
+	// ErrPathType is returned when a segment is traversing a non container value
+	ErrPathType = errors.New("path is not a container")
+)
+
+// PathError records the failing segment of a path, Segment is -1 when the
+// whole path is invalid, e.g. on syntax error
+type PathError struct {
+	Path    string
+	Segment int
+	Key     string
+	Err     error
+}
+
+func (e *PathError) Error() string {
+	if e.Segment < 0 {
+		return fmt.Sprintf("kitgo: path %q: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("kitgo: path %q: segment %d %q: %s", e.Path, e.Segment, e.Key, e.Err)
+}
+func (e *PathError) Unwrap() error { return e.Err }
+
+// GetPath resolve value given path, path is either a JSON Pointer (RFC 6901)
+// such as "/a/b/0/c" or a dotted form such as "a.b[0].c"
+func (d Dict) GetPath(path string) (interface{}, error) {
+	p, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.get(map[string]interface{}(d))
+}
+
+// SetPath set value given path, missing intermediate Dict or List are created,
+// the last segment of a List could be "-" or the len of List to append a value
+func (d *Dict) SetPath(path string, v interface{}) (err error) {
+	d.do(func() { err = setPath(d, path, v) })
+	return
+}
+
+// DeletePath remove value given path
+func (d *Dict) DeletePath(path string) (err error) {
+	d.do(func() { err = deletePath(d, path) })
+	return
+}
+
+// ExistsPath report whether value given path exists
+func (d Dict) ExistsPath(path string) bool {
+	_, err := d.GetPath(path)
+	return err == nil
+}
+
+// GetPath resolve value given path, see Dict.GetPath
+func (l List) GetPath(path string) (interface{}, error) {
+	p, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.get([]interface{}(l))
+}
+
+// SetPath set value given path, see Dict.SetPath
+func (l *List) SetPath(path string, v interface{}) (err error) {
+	l.do(func() { err = setPath(l, path, v) })
+	return
+}
+
+// DeletePath remove value given path, see Dict.DeletePath
+func (l *List) DeletePath(path string) (err error) {
+	l.do(func() { err = deletePath(l, path) })
+	return
+}
+
+// ExistsPath report whether value given path exists
+func (l List) ExistsPath(path string) bool {
+	_, err := l.GetPath(path)
+	return err == nil
+}
+
+// setPath and deletePath share the root handling of Dict and List
+func setPath(root interface{}, path string, v interface{}) error {
+	p, err := parsePath(path)
+	if err == nil {
+		_, err = p.update(root, true, func(node interface{}, i int) (interface{}, error) {
+			return p.put(node, i, v)
+		})
+	}
+	return err
+}
+func deletePath(root interface{}, path string) error {
+	p, err := parsePath(path)
+	if err == nil {
+		_, err = p.update(root, false, p.remove)
+	}
+	return err
+}
+
+// pathSegment is a single step of a path, index is true when the segment is
+// explicitly written as index e.g. "[0]", list is true when a missing value of
+// the segment should be created as a List, either an explicit index or a
+// JSON Pointer segment that looks like an index e.g. "/0" or "/-"
+type pathSegment struct {
+	key   string
+	index bool
+	list  bool
+}
+
+type jsonPath struct {
+	raw  string
+	segs []pathSegment
+}
+
+func parsePath(path string) (p jsonPath, err error) {
+	p.raw = path
+	if path == "" {
+		return
+	}
+	if path[0] == '/' {
+		for _, s := range strings.Split(path[1:], "/") {
+			if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(s, "~0", ""), "~1", ""), "~") {
+				return p, &PathError{path, -1, "", ErrPathSyntax}
+			}
+			s = strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+			_, err := strconv.Atoi(s)
+			p.segs = append(p.segs, pathSegment{s, false, s == "-" || err == nil})
+		}
+		return
+	}
+	syntax := &PathError{path, -1, "", ErrPathSyntax}
+	for i := 0; i < len(path); {
+		if path[i] == '[' {
+			j := strings.IndexByte(path[i:], ']')
+			if j < 0 {
+				return p, syntax
+			}
+			idx := path[i+1 : i+j]
+			if _, err := strconv.Atoi(idx); err != nil && idx != "-" {
+				return p, syntax
+			}
+			p.segs = append(p.segs, pathSegment{idx, true, true})
+			i += j + 1
+			continue
+		}
+		if path[i] == '.' && len(p.segs) > 0 {
+			i++
+		} else if len(p.segs) > 0 {
+			return p, syntax
+		}
+		b := new(strings.Builder)
+		for ; i < len(path) && path[i] != '.' && path[i] != '['; i++ {
+			if path[i] == '\\' && i+1 < len(path) {
+				i++
+			}
+			b.WriteByte(path[i])
+		}
+		if b.Len() < 1 {
+			return p, syntax
+		}
+		p.segs = append(p.segs, pathSegment{b.String(), false, false})
+	}
+	return
+}
+
+func (p jsonPath) err(i int, err error) error {
+	return &PathError{p.raw, i, p.segs[i].key, err}
+}
+
+// index parse segment i as an index of List with length n, "-" is equal to n
+func (p jsonPath) index(i, n int) (int, error) {
+	if p.segs[i].key == "-" {
+		return n, nil
+	}
+	k, err := strconv.Atoi(p.segs[i].key)
+	if err != nil || k < 0 || (len(p.segs[i].key) > 1 && p.segs[i].key[0] == '0') {
+		return 0, p.err(i, ErrPathIndex)
+	}
+	return k, nil
+}
+
+// child get the value of segment i within node
+func (p jsonPath) child(node interface{}, i int) (interface{}, error) {
+	switch n := node.(type) {
+	case Dict:
+		return p.child(map[string]interface{}(n), i)
+	case *Dict:
+		return p.child(map[string]interface{}(*n), i)
+	case map[string]interface{}:
+		if p.segs[i].index {
+			return nil, p.err(i, ErrPathType)
+		}
+		if v, ok := n[p.segs[i].key]; ok {
+			return v, nil
+		}
+		return nil, p.err(i, ErrPathNotFound)
+	case List:
+		return p.child([]interface{}(n), i)
+	case *List:
+		return p.child([]interface{}(*n), i)
+	case []interface{}:
+		k, err := p.index(i, len(n))
+		if err != nil {
+			return nil, err
+		}
+		if k >= len(n) {
+			return nil, p.err(i, ErrPathNotFound)
+		}
+		return n[k], nil
+	}
+	return nil, p.err(i, ErrPathType)
+}
+
+func (p jsonPath) get(node interface{}) (v interface{}, err error) {
+	for i := 0; i < len(p.segs) && err == nil; i++ {
+		node, err = p.child(node, i)
+	}
+	return node, err
+}
+
+// update walk the path and call fn on the container of the last segment, each
+// container is then put back into its parent since a List could be grown,
+// the updated root is returned
+func (p jsonPath) update(root interface{}, create bool, fn func(node interface{}, i int) (interface{}, error)) (interface{}, error) {
+	if len(p.segs) < 1 {
+		return nil, &PathError{p.raw, -1, "", ErrPathNotFound}
+	}
+	var walk func(node interface{}, i int) (interface{}, error)
+	walk = func(node interface{}, i int) (interface{}, error) {
+		if i == len(p.segs)-1 {
+			return fn(node, i)
+		}
+		next, err := p.child(node, i)
+		if create && errors.Is(err, ErrPathNotFound) {
+			next, err = Dict{}, nil
+			if p.segs[i+1].list {
+				next = List{}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next, err = walk(next, i+1); err != nil {
+			return nil, err
+		}
+		return p.put(node, i, next)
+	}
+	return walk(root, 0)
+}
+
+// put set v as segment i within node and return the updated node
+func (p jsonPath) put(node interface{}, i int, v interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case *Dict:
+		_, err := p.put(*n, i, v)
+		return n, err
+	case Dict:
+		_, err := p.put(map[string]interface{}(n), i, v)
+		return n, err
+	case map[string]interface{}:
+		if p.segs[i].index {
+			return nil, p.err(i, ErrPathType)
+		}
+		n[p.segs[i].key] = v
+		return n, nil
+	case *List:
+		l, err := p.put([]interface{}(*n), i, v)
+		if err == nil {
+			*n = l.([]interface{})
+		}
+		return n, err
+	case List:
+		l, err := p.put([]interface{}(n), i, v)
+		if err != nil {
+			return nil, err
+		}
+		return List(l.([]interface{})), nil
+	case []interface{}:
+		k, err := p.index(i, len(n))
+		if err != nil {
+			return nil, err
+		}
+		if k > len(n) {
+			return nil, p.err(i, ErrPathIndex)
+		}
+		if k == len(n) {
+			return append(n, v), nil
+		}
+		n[k] = v
+		return n, nil
+	}
+	return nil, p.err(i, ErrPathType)
+}
+
+// remove delete segment i within node and return the updated node
+func (p jsonPath) remove(node interface{}, i int) (interface{}, error) {
+	switch n := node.(type) {
+	case *Dict:
+		_, err := p.remove(*n, i)
+		return n, err
+	case Dict:
+		_, err := p.remove(map[string]interface{}(n), i)
+		return n, err
+	case map[string]interface{}:
+		if _, err := p.child(n, i); err != nil {
+			return nil, err
+		}
+		delete(n, p.segs[i].key)
+		return n, nil
+	case *List:
+		l, err := p.remove([]interface{}(*n), i)
+		if err == nil {
+			*n = l.([]interface{})
+		}
+		return n, err
+	case List:
+		l, err := p.remove([]interface{}(n), i)
+		if err != nil {
+			return nil, err
+		}
+		return List(l.([]interface{})), nil
+	case []interface{}:
+		if _, err := p.child(n, i); err != nil {
+			return nil, err
+		}
+		k, _ := p.index(i, len(n))
+		return append(n[:k:k], n[k+1:]...), nil
+	}
+	return nil, p.err(i, ErrPathType)
+}
@@ -0,0 +1,137 @@
+package kitgo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_dict_path(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	d := kitgo.Dict{}
+	Expect(kitgo.JSON.Unmarshal([]byte(`{
+		"a": {"b": [{"c": 1}, {"c": 2}]},
+		"x/y": {"m~n": true},
+		"k.l": "dotted",
+		"-": "dash"
+	}`), &d)).To(Succeed())
+
+	t.Run("get", func(t *testing.T) {
+		for path, expect := range map[string]interface{}{
+			"/a/b/0/c":     float64(1),
+			"a.b[1].c":     float64(2),
+			"a.b.1.c":      float64(2),
+			"/x~1y/m~0n":   true,
+			`x/y.m~n`:      true,
+			`k\.l`:         "dotted",
+			"/-":           "dash",
+			"-":            "dash",
+			"/a/b/1":       map[string]interface{}{"c": float64(2)},
+			"a.b[-1]":      nil,
+			"/a/b/01":      nil,
+			"/a/b/2":       nil,
+			"/a/b/0/c/d":   nil,
+			"a[0]":         nil,
+			"/a/z":         nil,
+			"/a/b/0/c/~2x": nil,
+		} {
+			v, err := d.GetPath(path)
+			if expect == nil {
+				Expect(err).To(HaveOccurred(), path)
+				Expect(d.ExistsPath(path)).To(BeFalse(), path)
+				continue
+			}
+			Expect(err).NotTo(HaveOccurred(), path)
+			Expect(v).To(Equal(expect), path)
+			Expect(d.ExistsPath(path)).To(BeTrue(), path)
+		}
+		v, err := d.GetPath("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal(map[string]interface{}(d)))
+	})
+	t.Run("syntax", func(t *testing.T) {
+		for _, path := range []string{".a", "a.", "a..b", "a[0", "a[x]", "a[0]b", "/a~"} {
+			_, err := d.GetPath(path)
+			Expect(errors.Is(err, kitgo.ErrPathSyntax)).To(BeTrue(), path)
+			pe := new(kitgo.PathError)
+			Expect(errors.As(err, &pe)).To(BeTrue())
+			Expect(pe.Segment).To(Equal(-1))
+			Expect(pe.Error()).To(ContainSubstring(path))
+		}
+	})
+	t.Run("typed errors", func(t *testing.T) {
+		_, err := d.GetPath("a.b[0].z")
+		pe := new(kitgo.PathError)
+		Expect(errors.As(err, &pe)).To(BeTrue())
+		Expect(pe.Segment).To(Equal(3))
+		Expect(pe.Key).To(Equal("z"))
+		Expect(errors.Is(err, kitgo.ErrPathNotFound)).To(BeTrue())
+		Expect(err.Error()).To(Equal(`kitgo: path "a.b[0].z": segment 3 "z": path not found`))
+
+		_, err = d.GetPath("a.b[0].c.d")
+		Expect(errors.Is(err, kitgo.ErrPathType)).To(BeTrue())
+		_, err = d.GetPath("/a/b/x")
+		Expect(errors.Is(err, kitgo.ErrPathIndex)).To(BeTrue())
+	})
+	t.Run("set", func(t *testing.T) {
+		var e kitgo.Dict
+		Expect(e.SetPath("a.b[0].c", 1)).To(Succeed())
+		Expect(e.SetPath("a.b[-].c", 2)).To(Succeed())
+		Expect(e.SetPath("/a/d/e", 3)).To(Succeed())
+		Expect(e.SetPath("/a/f/-", 4)).To(Succeed())
+		Expect(e.SetPath("/a/f/0", 5)).To(Succeed())
+		Expect(e).To(Equal(kitgo.Dict{"a": kitgo.Dict{
+			"b": kitgo.List{kitgo.Dict{"c": 1}, kitgo.Dict{"c": 2}},
+			"d": kitgo.Dict{"e": 3},
+			"f": kitgo.List{5},
+		}}))
+
+		Expect(d.SetPath("a.b[2].c", 3)).To(Succeed())
+		Expect(d.GetPath("/a/b/2/c")).To(Equal(3))
+		Expect(errors.Is(d.SetPath("a.b[4]", 0), kitgo.ErrPathIndex)).To(BeTrue())
+		Expect(errors.Is(d.SetPath("a.b[0].c.d", 0), kitgo.ErrPathType)).To(BeTrue())
+		Expect(errors.Is(d.SetPath("a[0]", 0), kitgo.ErrPathType)).To(BeTrue())
+		Expect(errors.Is(d.SetPath("", 0), kitgo.ErrPathNotFound)).To(BeTrue())
+		Expect(errors.Is(d.SetPath("a..", 0), kitgo.ErrPathSyntax)).To(BeTrue())
+	})
+	t.Run("delete", func(t *testing.T) {
+		e := kitgo.Dict{"a": kitgo.List{1, 2, 3}, "b": map[string]interface{}{"c": 1}}
+		Expect(e.DeletePath("a[1]")).To(Succeed())
+		Expect(e.DeletePath("/b/c")).To(Succeed())
+		Expect(e).To(Equal(kitgo.Dict{"a": kitgo.List{1, 3}, "b": map[string]interface{}{}}))
+		Expect(errors.Is(e.DeletePath("a[5]"), kitgo.ErrPathNotFound)).To(BeTrue())
+		Expect(errors.Is(e.DeletePath("/b/c"), kitgo.ErrPathNotFound)).To(BeTrue())
+		Expect(errors.Is(e.DeletePath("/a/0/b"), kitgo.ErrPathType)).To(BeTrue())
+		Expect(errors.Is(e.DeletePath("/a/0/b/c"), kitgo.ErrPathType)).To(BeTrue())
+		Expect(errors.Is(e.DeletePath("a["), kitgo.ErrPathSyntax)).To(BeTrue())
+
+		e = kitgo.Dict{"a": kitgo.Dict{"b": 1}, "c": kitgo.List{kitgo.List{}}}
+		Expect(e.DeletePath("a.b")).To(Succeed())
+		Expect(e.DeletePath("a")).To(Succeed())
+		Expect(errors.Is(e.SetPath("c[0][1]", 1), kitgo.ErrPathIndex)).To(BeTrue())
+		Expect(e).To(Equal(kitgo.Dict{"c": kitgo.List{kitgo.List{}}}))
+	})
+	t.Run("list", func(t *testing.T) {
+		var l kitgo.List
+		Expect(l.SetPath("[0].a", 1)).To(Succeed())
+		Expect(l.SetPath("/-", "x")).To(Succeed())
+		Expect(l.SetPath("/1", "y")).To(Succeed())
+		Expect(l.SetPath("[2][0]", "z")).To(Succeed())
+		Expect(l).To(Equal(kitgo.List{kitgo.Dict{"a": 1}, "y", kitgo.List{"z"}}))
+		Expect(l.GetPath("[0].a")).To(Equal(1))
+		Expect(l.ExistsPath("[2][0]")).To(BeTrue())
+		Expect(l.ExistsPath("[2][1]")).To(BeFalse())
+		Expect(l.DeletePath("[2][0]")).To(Succeed())
+		Expect(l.DeletePath("/0")).To(Succeed())
+		Expect(l).To(Equal(kitgo.List{"y", kitgo.List{}}))
+		_, err := l.GetPath("[")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(l.SetPath("a", 1), kitgo.ErrPathIndex)).To(BeTrue())
+		Expect(errors.Is(l.DeletePath("[5]"), kitgo.ErrPathNotFound)).To(BeTrue())
+		Expect(errors.Is(l.DeletePath("[0].a"), kitgo.ErrPathType)).To(BeTrue())
+	})
+}
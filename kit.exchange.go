@@ -0,0 +1,313 @@
+package kitgo
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateNotFound is returned when a provider has no rate for the pair
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+var Exchange exchange_
+
+type exchange_ struct{}
+
+// New return a converter backed by conf.Provider, which is required
+func (exchange_) New(conf *ExchangeConfig) *ExchangeWrapper {
+	PanicWhen(conf == nil || conf.Provider == nil, "kitgo: exchange provider is required")
+	return &ExchangeWrapper{conf.Provider}
+}
+
+// Memory return an in-memory provider, each pair keeps only its latest rate,
+// it panics when a rate is not positive
+func (exchange_) Memory(rates ...ExchangeRate) *ExchangeMemory {
+	x := &ExchangeMemory{rates: map[[2]string]float64{}}
+	err := x.Set(rates...)
+	PanicWhen(err != nil, err)
+	return x
+}
+
+// History return a provider of date-stamped rates loaded from each file, the
+// file is either a ".csv" or ".json", see ExchangeHistory.LoadCSV & LoadJSON
+func (exchange_) History(paths ...string) (*ExchangeHistory, error) {
+	x := &ExchangeHistory{rates: map[[2]string][]ExchangeRate{}}
+	for _, path := range paths {
+		if err := x.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+type ExchangeConfig struct {
+	// Provider resolve the rate for each conversion
+	Provider ExchangeRateI
+}
+
+// ExchangeRateI provide the rate to convert 1 unit of currency from into to,
+// as of the given time, from & to are ISO 4217 code, e.g. "USD"
+type ExchangeRateI interface {
+	Rate(ctx context.Context, from, to string, at time.Time) (float64, error)
+}
+
+// ExchangeRate is a rate of From into To, Date is when the rate takes effect
+type ExchangeRate struct {
+	From string
+	To   string
+	Rate float64
+	Date time.Time
+}
+
+type ExchangeWrapper struct{ provider ExchangeRateI }
+
+// Convert c into the currency of tag using the current rate
+func (x *ExchangeWrapper) Convert(ctx context.Context, c Currency, tag string) (Currency, error) {
+	return x.ConvertAt(ctx, c, tag, time.Now())
+}
+
+// ConvertAt convert c into the currency of tag using the rate as of at, the
+// value is rounded to the precision of the target currency, e.g. no decimal
+// for "ja", the same currency is converted with rate 1, Format is kept, and
+// ErrMoneyCurrency is returned when a tag resolve to no currency
+func (x *ExchangeWrapper) ConvertAt(ctx context.Context, c Currency, tag string, at time.Time) (Currency, error) {
+	units := [2]string{}
+	for i, t := range []string{c.Tag, tag} {
+		unit, ok := moneyCurrency(t)
+		if !ok {
+			return Currency{}, fmt.Errorf("kitgo: %w: %q", ErrMoneyCurrency, t)
+		}
+		units[i] = unit.String()
+	}
+	from, to := units[0], units[1]
+	rate := 1.0
+	if from != to {
+		var err error
+		if rate, err = x.provider.Rate(ctx, from, to, at); err != nil {
+			return Currency{}, err
+		}
+	}
+	m, err := Currency{Tag: tag, Value: c.Value * rate, Format: c.Format}.Money()
+	if err != nil {
+		return Currency{}, err
+	}
+	return m.Currency(), nil
+}
+
+// ExchangeMemory is an in-memory ExchangeRateI, the inverse of a pair is used
+// when the pair itself is not set, the date is ignored, the zero value is ready
+// to use
+type ExchangeMemory struct {
+	mu    sync.RWMutex
+	rates map[[2]string]float64
+}
+
+// Set store the rate of each pair, replacing the previous one, nothing is
+// stored when a rate is not positive
+func (x *ExchangeMemory) Set(rates ...ExchangeRate) error {
+	if err := checkExchangeRates(rates); err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.rates == nil {
+		x.rates = map[[2]string]float64{}
+	}
+	for _, r := range rates {
+		x.rates[[2]string{r.From, r.To}] = r.Rate
+	}
+	return nil
+}
+
+func (x *ExchangeMemory) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	var _ ExchangeRateI = x
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if r, ok := x.rates[[2]string{from, to}]; ok {
+		return r, nil
+	}
+	if r, ok := x.rates[[2]string{to, from}]; ok && r != 0 {
+		return 1 / r, nil
+	}
+	return 0, fmt.Errorf("kitgo: %w: %s/%s", ErrRateNotFound, from, to)
+}
+
+// ExchangeHistory is an ExchangeRateI of date-stamped rates, the rate as of a
+// time is the latest one whose Date is not after it, the inverse of a pair is
+// used when the pair itself has no rate, the zero value is ready to use
+type ExchangeHistory struct {
+	mu    sync.RWMutex
+	rates map[[2]string][]ExchangeRate
+}
+
+// Add store each rate, a rate of the same pair & Date is replaced, nothing is
+// stored when a rate is not positive
+func (x *ExchangeHistory) Add(rates ...ExchangeRate) error {
+	if err := checkExchangeRates(rates); err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.rates == nil {
+		x.rates = map[[2]string][]ExchangeRate{}
+	}
+	for _, r := range rates {
+		k := [2]string{r.From, r.To}
+		l := x.rates[k]
+		i := sort.Search(len(l), func(i int) bool { return !l[i].Date.Before(r.Date) })
+		if i < len(l) && l[i].Date.Equal(r.Date) {
+			l[i] = r
+			continue
+		}
+		x.rates[k] = append(l[:i:i], append([]ExchangeRate{r}, l[i:]...)...)
+	}
+	return nil
+}
+
+// LoadCSV add rates from csv with header "date,from,to,rate" in any order,
+// date is either "2006-01-02" or RFC3339
+func (x *ExchangeHistory) LoadCSV(r io.Reader) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < 1 {
+		return nil
+	}
+	col := map[string]int{}
+	for i, h := range records[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, h := range []string{"date", "from", "to", "rate"} {
+		if _, ok := col[h]; !ok {
+			return fmt.Errorf("kitgo: exchange csv: missing column %q", h)
+		}
+	}
+	var errs errorList
+	rates := make([]ExchangeRate, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		r, err := parseExchangeRate(rec[col["date"]], rec[col["from"]], rec[col["to"]], rec[col["rate"]])
+		if err != nil {
+			errs = errs.Append(fmt.Errorf("kitgo: exchange csv: line %d: %w", i+2, err))
+			continue
+		}
+		rates = append(rates, r)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return x.Add(rates...)
+}
+
+// LoadJSON add rates from json array of {"date","from","to","rate"}, rate
+// could be either number or string
+func (x *ExchangeHistory) LoadJSON(r io.Reader) error {
+	var v []struct {
+		Date string      `json:"date"`
+		From string      `json:"from"`
+		To   string      `json:"to"`
+		Rate interface{} `json:"rate"`
+	}
+	if err := JSON.NewDecoder(r).Decode(&v); err != nil {
+		return err
+	}
+	var errs errorList
+	rates := make([]ExchangeRate, 0, len(v))
+	for i := range v {
+		rate, _ := toString("rate", v[i].Rate)
+		r, err := parseExchangeRate(v[i].Date, v[i].From, v[i].To, rate)
+		if err != nil {
+			errs = errs.Append(fmt.Errorf("kitgo: exchange json: index %d: %w", i, err))
+			continue
+		}
+		rates = append(rates, r)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return x.Add(rates...)
+}
+
+func (x *ExchangeHistory) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	var _ ExchangeRateI = x
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if r, ok := x.asOf(from, to, at); ok {
+		return r, nil
+	}
+	if r, ok := x.asOf(to, from, at); ok && r != 0 {
+		return 1 / r, nil
+	}
+	return 0, fmt.Errorf("kitgo: %w: %s/%s as of %s", ErrRateNotFound, from, to, at.Format(time.RFC3339))
+}
+
+func (x *ExchangeHistory) asOf(from, to string, at time.Time) (float64, bool) {
+	l := x.rates[[2]string{from, to}]
+	i := sort.Search(len(l), func(i int) bool { return l[i].Date.After(at) })
+	if i < 1 {
+		return 0, false
+	}
+	return l[i-1].Rate, true
+}
+
+func (x *ExchangeHistory) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return x.LoadCSV(f)
+	case ".json":
+		return x.LoadJSON(f)
+	default:
+		return fmt.Errorf("kitgo: exchange file %q: unknown extension %q", path, ext)
+	}
+}
+
+func parseExchangeRate(date, from, to, rate string) (ExchangeRate, error) {
+	r := ExchangeRate{From: strings.ToUpper(strings.TrimSpace(from)), To: strings.ToUpper(strings.TrimSpace(to))}
+	if r.From == "" || r.To == "" {
+		return r, fmt.Errorf("missing currency code")
+	}
+	var err error
+	if r.Date, err = toTime("date", date); err != nil {
+		return r, err
+	}
+	if r.Rate, err = strconv.ParseFloat(strings.TrimSpace(rate), 64); err != nil {
+		return r, err
+	}
+	return r, checkExchangeRate(r)
+}
+
+func checkExchangeRate(r ExchangeRate) error {
+	if !(r.Rate > 0) {
+		return fmt.Errorf("rate %v is not positive", r.Rate)
+	}
+	return nil
+}
+
+func checkExchangeRates(rates []ExchangeRate) error {
+	for _, r := range rates {
+		if err := checkExchangeRate(r); err != nil {
+			return fmt.Errorf("kitgo: exchange %s/%s: %w", r.From, r.To, err)
+		}
+	}
+	return nil
+}
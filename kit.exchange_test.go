@@ -0,0 +1,145 @@
+package kitgo_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_exchange(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+	ctx := context.Background()
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		Expect(err).NotTo(HaveOccurred())
+		return d
+	}
+
+	t.Run("New", func(t *testing.T) {
+		Expect(func() { kitgo.Exchange.New(nil) }).To(Panic())
+		Expect(func() { kitgo.Exchange.New(&kitgo.ExchangeConfig{}) }).To(Panic())
+	})
+	t.Run("Memory", func(t *testing.T) {
+		mem := kitgo.Exchange.Memory(
+			kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 14000},
+			kitgo.ExchangeRate{From: "USD", To: "JPY", Rate: 110.123},
+		)
+		x := kitgo.Exchange.New(&kitgo.ExchangeConfig{Provider: mem})
+
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1.5}, "id")).To(Equal(kitgo.Currency{Tag: "id", Value: 21000}))
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1.5}, "ja")).To(Equal(kitgo.Currency{Tag: "ja", Value: 165}))
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "id", Value: 10000}, "en-US")).To(Equal(kitgo.Currency{Tag: "en-US", Value: 0.71}))
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1.006}, "es-US")).To(Equal(kitgo.Currency{Tag: "es-US", Value: 1.01}))
+
+		Expect(mem.Set(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 15000})).To(Succeed())
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1}, "id")).To(Equal(kitgo.Currency{Tag: "id", Value: 15000}))
+
+		_, err := x.Convert(ctx, kitgo.Currency{Tag: "id", Value: 1}, "ja")
+		Expect(errors.Is(err, kitgo.ErrRateNotFound)).To(BeTrue())
+		Expect(err.Error()).To(Equal("kitgo: exchange rate not found: IDR/JPY"))
+		// a tag without currency is never converted, even into another one
+		for _, tags := range [][2]string{{"foo", "bar"}, {"??", "en-US"}, {"en-US", "??"}} {
+			_, err = x.Convert(ctx, kitgo.Currency{Tag: tags[0], Value: 1}, tags[1])
+			Expect(errors.Is(err, kitgo.ErrMoneyCurrency)).To(BeTrue(), "%v", tags)
+		}
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1, Format: "%[2]s %[1]s"}, "id")).
+			To(Equal(kitgo.Currency{Tag: "id", Value: 15000, Format: "%[2]s %[1]s"}))
+
+		_, err = x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1e300}, "id")
+		Expect(errors.Is(err, kitgo.ErrMoneyOverflow)).To(BeTrue())
+
+		// a rate which is not positive is rejected, along with the others
+		Expect(mem.Set(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 1}, kitgo.ExchangeRate{From: "USD", To: "JPY", Rate: 0})).
+			To(MatchError("kitgo: exchange USD/JPY: rate 0 is not positive"))
+		Expect(x.Convert(ctx, kitgo.Currency{Tag: "en-US", Value: 1}, "id")).To(Equal(kitgo.Currency{Tag: "id", Value: 15000}))
+		Expect(func() { kitgo.Exchange.Memory(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: -1}) }).To(Panic())
+
+		// the zero value is ready to use
+		zero := &kitgo.ExchangeMemory{}
+		Expect(zero.Set(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 15000})).To(Succeed())
+		Expect(zero.Rate(ctx, "IDR", "USD", time.Time{})).To(Equal(1 / 15000.0))
+
+		cancel, stop := context.WithCancel(ctx)
+		stop()
+		_, err = x.Convert(cancel, kitgo.Currency{Tag: "id", Value: 1}, "en-US")
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+	t.Run("History", func(t *testing.T) {
+		dir := t.TempDir()
+		write := func(name, content string) string {
+			path := filepath.Join(dir, name)
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+			return path
+		}
+		csvPath := write("rates.csv", "Rate,From,To,Date\n14000,usd,idr,2021-01-01\n14500,USD,IDR,2021-02-01T00:00:00Z\n")
+		jsonPath := write("rates.json", `[{"date":"2021-03-01","from":"USD","to":"IDR","rate":"15000"},{"date":"2021-01-01","from":"EUR","to":"USD","rate":1.25}]`)
+
+		hist, err := kitgo.Exchange.History(csvPath, jsonPath)
+		Expect(err).NotTo(HaveOccurred())
+		x := kitgo.Exchange.New(&kitgo.ExchangeConfig{Provider: hist})
+		usd := kitgo.Currency{Tag: "en-US", Value: 2}
+		for date, expect := range map[string]float64{
+			"2021-01-01": 28000,
+			"2021-01-31": 28000,
+			"2021-02-01": 29000,
+			"2021-03-01": 30000,
+			"2022-01-01": 30000,
+		} {
+			Expect(x.ConvertAt(ctx, usd, "id", day(date))).To(Equal(kitgo.Currency{Tag: "id", Value: expect}), date)
+		}
+		Expect(x.ConvertAt(ctx, kitgo.Currency{Tag: "id", Value: 29000}, "en-US", day("2021-02-15"))).To(Equal(kitgo.Currency{Tag: "en-US", Value: 2}))
+		Expect(x.ConvertAt(ctx, kitgo.Currency{Tag: "de-DE", Value: 2}, "en-US", day("2021-02-15"))).To(Equal(kitgo.Currency{Tag: "en-US", Value: 2.5}))
+		Expect(x.Convert(ctx, usd, "id")).To(Equal(kitgo.Currency{Tag: "id", Value: 30000}))
+
+		_, err = x.ConvertAt(ctx, usd, "id", day("2020-12-31"))
+		Expect(errors.Is(err, kitgo.ErrRateNotFound)).To(BeTrue())
+		Expect(err.Error()).To(Equal("kitgo: exchange rate not found: USD/IDR as of 2020-12-31T00:00:00Z"))
+
+		// replace the rate of the same date
+		Expect(hist.Add(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 1, Date: day("2021-03-01")})).To(Succeed())
+		Expect(x.ConvertAt(ctx, usd, "id", day("2021-03-01"))).To(Equal(kitgo.Currency{Tag: "id", Value: 2}))
+		Expect(hist.Add(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: -2, Date: day("2021-03-01")})).
+			To(MatchError("kitgo: exchange USD/IDR: rate -2 is not positive"))
+		Expect(x.ConvertAt(ctx, usd, "id", day("2021-03-01"))).To(Equal(kitgo.Currency{Tag: "id", Value: 2}))
+
+		// the zero value is ready to use
+		zero := &kitgo.ExchangeHistory{}
+		Expect(zero.Add(kitgo.ExchangeRate{From: "USD", To: "IDR", Rate: 15000, Date: day("2021-01-01")})).To(Succeed())
+		Expect(zero.Rate(ctx, "USD", "IDR", day("2021-01-02"))).To(Equal(15000.0))
+
+		cancel, stop := context.WithCancel(ctx)
+		stop()
+		_, err = x.Convert(cancel, usd, "id")
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+
+		for _, tt := range []struct{ name, content, expect string }{
+			{"empty.csv", "", ""},
+			{"quote.csv", `"`, "extraneous or missing"},
+			{"header.csv", "date,from,to\n", `kitgo: exchange csv: missing column "rate"`},
+			{"line.csv", "date,from,to,rate\n2021-01-01,,IDR,1\nx,USD,IDR,1\n2021-01-01,USD,IDR,x\n2021-01-01,USD,IDR,-1\n", "kitgo: exchange csv: line 2: missing currency code\nkitgo: exchange csv: line 3: "},
+			{"syntax.json", `{`, "decode slice"},
+			{"index.json", `[{"date":"2021-01-01","from":"USD","to":"IDR","rate":0}]`, "kitgo: exchange json: index 0: rate 0 is not positive"},
+			{"rates.txt", "", `unknown extension ".txt"`},
+		} {
+			_, err := kitgo.Exchange.History(write(tt.name, tt.content))
+			if tt.expect == "" {
+				Expect(err).NotTo(HaveOccurred(), tt.name)
+				continue
+			}
+			Expect(err).To(HaveOccurred(), tt.name)
+			Expect(err.Error()).To(ContainSubstring(tt.expect), tt.name)
+		}
+		_, err = kitgo.Exchange.History(filepath.Join(dir, "none.csv"))
+		Expect(err).To(HaveOccurred())
+
+		Expect(hist.LoadJSON(strings.NewReader(`[]`))).To(Succeed())
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
@@ -79,7 +81,8 @@ func NewErrors(errs ...error) errorList {
 	return errorList(nil).Append(errs...)
 }
 
-// errorList is a wrapper to slice of `error`
+// errorList is a wrapper to slice of `error`, it is traversable by errors.Is
+// and errors.As, each error is checked in order
 type errorList []error
 
 func (e errorList) Append(errs ...error) errorList {
@@ -100,25 +103,125 @@ func (e errorList) Error() string {
 	}
 	return strings.Join(v, "\n")
 }
+func (e errorList) Is(target error) bool {
+	for i := range e {
+		if errors.Is(e[i], target) {
+			return true
+		}
+	}
+	return false
+}
+func (e errorList) As(target interface{}) bool {
+	for i := range e {
+		if errors.As(e[i], target) {
+			return true
+		}
+	}
+	return false
+}
+func (e errorList) Unwrap() []error { return append([]error(nil), e...) }
+
+// MarshalJSON encode each *Error as an object and any other error as string
 func (e errorList) MarshalJSON() ([]byte, error) {
 	var _ json.Marshaler = e
-	var v []string
+	var v []interface{}
 	for i := range e {
-		if e[i] != nil {
+		if err, ok := e[i].(*Error); ok && err != nil {
+			v = append(v, err)
+		} else if e[i] != nil {
 			v = append(v, e[i].Error())
 		}
 	}
 	return JSON.Marshal(v)
 }
+
+// UnmarshalJSON decode string as plain error and object as *Error, the list
+// is replaced only when every element is decoded
 func (e *errorList) UnmarshalJSON(b []byte) error {
 	var _ json.Unmarshaler = e
-	v := []string{}
-	err := JSON.Unmarshal(b, &v)
+	v := []json.RawMessage{}
+	if err := JSON.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var list errorList
 	for i := range v {
-		if v[i] != "" {
-			*e = append(*e, fmt.Errorf(v[i]))
+		if len(v[i]) > 0 && v[i][0] == '{' {
+			x := new(Error)
+			if err := x.UnmarshalJSON(v[i]); err != nil {
+				return err
+			}
+			list = append(list, x)
+			continue
+		}
+		var s string
+		if err := JSON.Unmarshal(v[i], &s); err != nil {
+			return err
+		}
+		if s != "" {
+			list = append(list, errors.New(s))
 		}
 	}
+	*e = list
+	return nil
+}
+
+// Error is a machine-readable error, Code identify the kind of error, Path
+// locate the offending field e.g. JSON Pointer, Meta carry extra detail and
+// Err is the underlying cause which is not serialized
+//
+// errors.Is match another *Error with the same non-empty Code
+type Error struct {
+	Code    string
+	Path    string
+	Message string
+	Meta    Dict
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Path != "" {
+		return e.Path + ": " + e.message()
+	}
+	return e.message()
+}
+func (e *Error) Unwrap() error { return e.Err }
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+func (e *Error) message() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	}
+	return e.Code
+}
+
+type errorJSON struct {
+	Code    string `json:"code,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	Meta    *Dict  `json:"meta,omitempty"`
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	var _ json.Marshaler = e
+	v := errorJSON{Code: e.Code, Path: e.Path, Message: e.message()}
+	if len(e.Meta) > 0 {
+		v.Meta = &e.Meta
+	}
+	return JSON.Marshal(v)
+}
+func (e *Error) UnmarshalJSON(b []byte) error {
+	var _ json.Unmarshaler = e
+	v := errorJSON{}
+	err := JSON.Unmarshal(b, &v)
+	*e = Error{Code: v.Code, Path: v.Path, Message: v.Message}
+	if v.Meta != nil {
+		e.Meta = *v.Meta
+	}
 	return err
 }
 
@@ -156,22 +259,16 @@ func (c Currency) String() string {
 	region, _ := tag.Region()
 	printer := message.NewPrinter(tag)
 	formatter := currency.Symbol
-	fallback := "%.2f"
-	if f, ok := map[string]string{
-		"BH": "%.3f", "IQ": "%.3f", "JO": "%.3f", "KW": "%.3f", "LY": "%.3f", "OM": "%.3f",
-		"TN": "%.3f", "BI": "%.0f", "CL": "%.0f", "DJ": "%.0f", "GN": "%.0f", "IS": "%.0f",
-		"JP": "%.0f", "KM": "%.0f", "KR": "%.0f", "PY": "%.0f", "RW": "%.0f", "UG": "%.0f",
-		"VN": "%.0f", "VU": "%.0f", "CM": "%.0f", "CF": "%.0f", "CG": "%.0f", "TD": "%.0f",
-		"GQ": "%.0f", "GA": "%.0f", "BJ": "%.0f", "BF": "%.0f", "CI": "%.0f", "GW": "%.0f",
-		"ML": "%.0f", "NE": "%.0f", "SN": "%.0f", "TG": "%.0f", "PF": "%.0f", "NC": "%.0f",
-		"WF": "%.0f",
-	}[region.String()]; ok {
-		fallback = f
-	}
+	fallback := fmt.Sprintf("%%.%df", currencyDecimals(region.String()))
 	str := fmt.Sprintf(c.Format,
 		printer.Sprint(formatter(unit.Amount(nil))),           // sign
 		printer.Sprintf(message.Key("%s", fallback), c.Value), // value
 	)
+	return normalizeSpace(str)
+}
+
+// normalizeSpace replace any unicode space e.g. non-breaking space with ' '
+func normalizeSpace(str string) string {
 	b := new(strings.Builder)
 	b.Grow(len(str))
 	for _, r := range str {
@@ -180,10 +277,28 @@ func (c Currency) String() string {
 		}
 		b.WriteRune(r)
 	}
-	// return str
 	return b.String()
 }
 
+// currencyDecimal is the number of minor unit digits per region, any region
+// not listed has 2 digits
+var currencyDecimal = map[string]int{
+	"BH": 3, "IQ": 3, "JO": 3, "KW": 3, "LY": 3, "OM": 3,
+	"TN": 3, "BI": 0, "CL": 0, "DJ": 0, "GN": 0, "IS": 0,
+	"JP": 0, "KM": 0, "KR": 0, "PY": 0, "RW": 0, "UG": 0,
+	"VN": 0, "VU": 0, "CM": 0, "CF": 0, "CG": 0, "TD": 0,
+	"GQ": 0, "GA": 0, "BJ": 0, "BF": 0, "CI": 0, "GW": 0,
+	"ML": 0, "NE": 0, "SN": 0, "TG": 0, "PF": 0, "NC": 0,
+	"WF": 0,
+}
+
+func currencyDecimals(region string) int {
+	if d, ok := currencyDecimal[region]; ok {
+		return d
+	}
+	return 2
+}
+
 // =============================================================================
 // Public
 // =============================================================================
@@ -197,7 +312,8 @@ var JSON jsoniter.API = jsoniter.ConfigFastest
 // if receiving error channel is unbuffered, it will block the current execution
 // process, would be better if receiving error channel is buffered with len(tasks)
 //
-// context is also cancelable and this is to run in go routine
+// context is also cancelable and this is to run in go routine, see ParallelPool
+// for a bounded, context-aware variant that collect results
 func Parallel(ctx context.Context, onError func(int, error), tasks ...func() error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -239,6 +355,7 @@ func RecoverWith(catch func(recv interface{})) {
 func ListenToSignal(sigs ...os.Signal) os.Signal {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
 	return <-ch
 }
 
@@ -258,12 +375,28 @@ func (x Base64Wrapper) BtoA(b []byte) []byte {
 	x.b64.Encode(enc, b)
 	return enc
 }
+
+// AtoB return the decoded bytes, the bytes decoded before an invalid input are
+// returned as is, use Decode to get the error
 func (x Base64Wrapper) AtoB(b []byte) []byte {
-	dec := make([]byte, x.b64.DecodedLen(len(b)))
-	_, _ = x.b64.Decode(dec, b)
+	dec, _ := x.Decode(b)
 	return dec
 }
 
+// Decode return the decoded bytes and the error of an invalid input
+func (x Base64Wrapper) Decode(b []byte) ([]byte, error) {
+	dec := make([]byte, x.b64.DecodedLen(len(b)))
+	n, err := x.b64.Decode(dec, b)
+	return dec[:n], err
+}
+
+// NewEncoder return a stream encoder writing to w, Close should be called to
+// flush the partially written block
+func (x Base64Wrapper) NewEncoder(w io.Writer) io.WriteCloser { return base64.NewEncoder(x.b64, w) }
+
+// NewDecoder return a stream decoder reading from r
+func (x Base64Wrapper) NewDecoder(r io.Reader) io.Reader { return base64.NewDecoder(x.b64, r) }
+
 // =============================================================================
 // Testing
 // =============================================================================
@@ -280,80 +413,3 @@ func ShouldCover(code int, required float64) int {
 	}
 	return code
 }
-
-var _ = [][2]string{
-	{".aac", "audio/aac"},
-	{".abw", "application/x-abiword"},
-	{".arc", "application/x-freearc"},
-	{".avi", "video/x-msvideo"},
-	{".azw", "application/vnd.amazon.ebook"},
-	{".bin", "application/octet-stream"},
-	{".bmp", "image/bmp"},
-	{".bz", "application/x-bzip"},
-	{".bz2", "application/x-bzip2"},
-	{".csh", "application/x-csh"},
-	{".css", "text/css"},
-	{".csv", "text/csv"},
-	{".doc", "application/msword"},
-	{".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
-	{".eot", "application/vnd.ms-fontobject"},
-	{".epub", "application/epub+zip"},
-	{".gz", "application/gzip"},
-	{".gif", "image/gif"},
-	{".htm", "text/html"},
-	{".html", "text/html"},
-	{".ico", "image/vnd.microsoft.icon"},
-	{".ics", "text/calendar"},
-	{".jar", "application/java-archive"},
-	{".jpeg", "image/jpeg"},
-	{".jpg", "image/jpeg"},
-	{".js", "text/javascript"},
-	{".json", "application/json"},
-	{".jsonld", "application/ld+json"},
-	{".mid", "audio/midi"},
-	{".midi", "audio/midi"},
-	{".mjs", "text/javascript"},
-	{".mp3", "audio/mpeg"},
-	{".mpeg", "video/mpeg"},
-	{".mpkg", "application/vnd.apple.installer+xml"},
-	{".odp", "application/vnd.oasis.opendocument.presentation"},
-	{".ods", "application/vnd.oasis.opendocument.spreadsheet"},
-	{".odt", "application/vnd.oasis.opendocument.text"},
-	{".oga", "audio/ogg"},
-	{".ogv", "video/ogg"},
-	{".ogx", "application/ogg"},
-	{".opus", "audio/opus"},
-	{".otf", "font/otf"},
-	{".png", "image/png"},
-	{".pdf", "application/pdf"},
-	{".php", "application/x-httpd-php"},
-	{".ppt", "application/vnd.ms-powerpoint"},
-	{".pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
-	{".rar", "application/vnd.rar"},
-	{".rtf", "application/rtf"},
-	{".sh", "application/x-sh"},
-	{".svg", "image/svg+xml"},
-	{".swf", "application/x-shockwave-flash"},
-	{".tar", "application/x-tar"},
-	{".tif", "image/tiff"},
-	{".tiff", "image/tiff"},
-	{".ts", "video/mp2t"},
-	{".ttf", "font/ttf"},
-	{".txt", "text/plain"},
-	{".vsd", "application/vnd.visio"},
-	{".wav", "audio/wav"},
-	{".weba", "audio/webm"},
-	{".webm", "video/webm"},
-	{".webp", "image/webp"},
-	{".woff", "font/woff"},
-	{".woff2", "font/woff2"},
-	{".xhtml", "application/xhtml+xml"},
-	{".xls", "application/vnd.ms-excel"},
-	{".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
-	{".xml", "text/xml"},
-	{".xul", "application/vnd.mozilla.xul+xml"},
-	{".zip", "application/zip"},
-	{".3gp", "video/3gpp"},
-	{".3g2", "video/3gpp2"},
-	{".7z", "application/x-7z-compressed"},
-}
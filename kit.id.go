@@ -0,0 +1,440 @@
+package kitgo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidID is returned when parsing or scanning a malformed ULID, UUID or
+// Snowflake
+var ErrInvalidID = errors.New("invalid id")
+
+// ErrIDEpoch is returned by IDGenerator.Snowflake when the clock is before
+// the Epoch
+var ErrIDEpoch = errors.New("clock is before the id epoch")
+
+var ID id_
+
+type id_ struct{}
+
+// idDefault is used by the shortcuts of ID
+var idDefault = ID.New(nil)
+
+// IDConfig configure ID.New, the zero value is ready to use
+type IDConfig struct {
+	// Node identify the generator in a Snowflake, it should be unique across
+	// the running processes and fit into NodeBits
+	Node int64
+
+	// NodeBits is the number of bits of a Snowflake used by Node, between 1
+	// and 21, the remaining of the 22 bits is the sequence, default to 10
+	NodeBits int
+
+	// Epoch is the origin of the Snowflake timestamp, default to 2020-01-01 UTC
+	Epoch time.Time
+
+	// Now default to time.Now
+	Now func() time.Time
+
+	// Entropy return length random bytes, default to CryptoWrapper.Nonce
+	Entropy func(length int) []byte
+}
+
+// New return a generator safe for concurrent use, every ID it returns is
+// greater than the previous one of the same kind (except UUIDv4), when the
+// clock go backward or the counter overflow within a millisecond, the
+// timestamp of the last ID is reused or incremented instead of waiting
+func (id_) New(conf *IDConfig) *IDGenerator {
+	if conf == nil {
+		conf = &IDConfig{}
+	}
+	x := &IDGenerator{node: conf.Node, nodeBits: uint(conf.NodeBits), epoch: conf.Epoch, now: conf.Now, entropy: conf.Entropy}
+	if x.nodeBits == 0 {
+		x.nodeBits = 10
+	}
+	PanicWhen(conf.NodeBits < 0 || conf.NodeBits > 21, "kitgo: id node bits should be between 1 and 21")
+	PanicWhen(x.node < 0 || x.node >= 1<<x.nodeBits, "kitgo: id node does not fit into node bits")
+	if x.epoch.IsZero() {
+		x.epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if x.now == nil {
+		x.now = time.Now
+	}
+	if x.entropy == nil {
+		x.entropy = Crypto.New().Nonce
+	}
+	return x
+}
+
+// ULID return a new ULID from the default generator
+func (id_) ULID() ULID { return idDefault.ULID() }
+
+// UUID return a new version 7 UUID from the default generator
+func (id_) UUID() UUID { return idDefault.UUIDv7() }
+
+// UUIDv4 return a new random UUID from the default generator
+func (id_) UUIDv4() UUID { return idDefault.UUIDv4() }
+
+// ParseULID parse the 26 characters Crockford's base32 form, case-insensitive
+func (id_) ParseULID(s string) (ULID, error) {
+	var u ULID
+	var hi, lo uint64
+	if len(s) != 26 {
+		return u, fmt.Errorf("kitgo: %w: %q is not a ULID", ErrInvalidID, s)
+	}
+	for i := 0; i < len(s); i++ {
+		v := idCrockfordIndex[s[i]]
+		if v < 0 || (i == 0 && v > 7) {
+			return u, fmt.Errorf("kitgo: %w: %q is not a ULID", ErrInvalidID, s)
+		}
+		hi, lo = hi<<5|lo>>59, lo<<5|uint64(v)
+	}
+	binary.BigEndian.PutUint64(u[:8], hi)
+	binary.BigEndian.PutUint64(u[8:], lo)
+	return u, nil
+}
+
+// ParseUUID parse the hyphenated or the 32 hexadecimal digits form,
+// case-insensitive
+func (id_) ParseUUID(s string) (UUID, error) {
+	var u UUID
+	h := s
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		h = s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(h) != 32 {
+		return u, fmt.Errorf("kitgo: %w: %q is not a UUID", ErrInvalidID, s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return UUID{}, fmt.Errorf("kitgo: %w: %q is not a UUID", ErrInvalidID, s)
+	}
+	return u, nil
+}
+
+// ParseSnowflake parse the decimal form
+func (id_) ParseSnowflake(s string) (Snowflake, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("kitgo: %w: %q is not a Snowflake", ErrInvalidID, s)
+	}
+	return Snowflake(n), nil
+}
+
+type IDGenerator struct {
+	mu       sync.Mutex
+	node     int64
+	nodeBits uint
+	epoch    time.Time
+	now      func() time.Time
+	entropy  func(int) []byte
+
+	ulid, uuid, snowflake idCounter
+}
+
+// ULID return a new ULID, the 80 bits entropy is random on a new millisecond
+// then incremented by one within the same millisecond
+func (x *IDGenerator) ULID() ULID {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.ulid.next(x.millis(), 1<<16-1, 1<<64-1, func() (uint64, uint64) {
+		b := x.random(10)
+		return uint64(binary.BigEndian.Uint16(b)), binary.BigEndian.Uint64(b[2:])
+	})
+	var u ULID
+	binary.BigEndian.PutUint64(u[:8], uint64(x.ulid.ms)<<16|x.ulid.hi)
+	binary.BigEndian.PutUint64(u[8:], x.ulid.lo)
+	return u
+}
+
+// UUIDv7 return a new version 7 UUID, the 74 bits after the timestamp are
+// random on a new millisecond then incremented by one within the same
+// millisecond, the most significant one start cleared to leave room for it
+func (x *IDGenerator) UUIDv7() UUID {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.uuid.next(x.millis(), 1<<12-1, 1<<62-1, func() (uint64, uint64) {
+		b := x.random(10)
+		return uint64(binary.BigEndian.Uint16(b)) & (1<<11 - 1), binary.BigEndian.Uint64(b[2:]) & (1<<62 - 1)
+	})
+	var u UUID
+	binary.BigEndian.PutUint64(u[:8], uint64(x.uuid.ms)<<16|0x7000|x.uuid.hi)
+	binary.BigEndian.PutUint64(u[8:], 1<<63|x.uuid.lo)
+	return u
+}
+
+// UUIDv4 return a new random UUID
+func (x *IDGenerator) UUIDv4() UUID {
+	var u UUID
+	copy(u[:], x.random(16))
+	u[6], u[8] = u[6]&0x0f|0x40, u[8]&0x3f|0x80
+	return u
+}
+
+// Snowflake return a new Snowflake made of 41 bits of milliseconds since
+// Epoch, NodeBits of Node and the remaining bits of sequence, ErrIDEpoch is
+// returned when the clock is before Epoch
+func (x *IDGenerator) Snowflake() (Snowflake, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := x.now()
+	ms := idMillis(now) - idMillis(x.epoch)
+	if ms < 0 {
+		return 0, fmt.Errorf("kitgo: %w: %s before %s", ErrIDEpoch, now.UTC().Format(time.RFC3339Nano), x.epoch.UTC().Format(time.RFC3339Nano))
+	}
+	seqBits := 22 - x.nodeBits
+	x.snowflake.next(ms, 0, 1<<seqBits-1, func() (uint64, uint64) { return 0, 0 })
+	return Snowflake((x.snowflake.ms&(1<<41-1))<<22 | x.node<<seqBits | int64(x.snowflake.lo)), nil
+}
+
+// SnowflakeParts is a Snowflake decoded by IDGenerator.Parts
+type SnowflakeParts struct {
+	Time     time.Time
+	Node     int64
+	Sequence int64
+}
+
+// Parts decode a Snowflake using the Epoch and NodeBits of the generator
+func (x *IDGenerator) Parts(s Snowflake) SnowflakeParts {
+	seqBits := 22 - x.nodeBits
+	return SnowflakeParts{
+		Time:     x.epoch.Add(time.Duration(s>>22) * time.Millisecond).UTC(),
+		Node:     int64(s) >> seqBits & (1<<x.nodeBits - 1),
+		Sequence: int64(s) & (1<<seqBits - 1),
+	}
+}
+
+func (x *IDGenerator) millis() int64 { return idMillis(x.now()) }
+
+func (x *IDGenerator) random(length int) []byte {
+	b := make([]byte, length)
+	copy(b, x.entropy(length))
+	return b
+}
+
+// idCounter is the state of a monotonic ID, hi and lo form a counter within
+// the millisecond ms
+type idCounter struct {
+	ms     int64
+	hi, lo uint64
+}
+
+// next move to the millisecond ms and seed the counter, or when ms is not
+// after the previous one, increment the counter, the millisecond is
+// incremented when the counter overflow hiMask and loMask
+func (c *idCounter) next(ms int64, hiMask, loMask uint64, seed func() (uint64, uint64)) {
+	switch {
+	case ms > c.ms:
+		c.ms = ms
+		c.hi, c.lo = seed()
+	case c.lo < loMask:
+		c.lo++
+	case c.hi < hiMask:
+		c.hi, c.lo = c.hi+1, 0
+	default:
+		c.ms++
+		c.hi, c.lo = seed()
+	}
+}
+
+func idMillis(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+
+func idTime(ms int64) time.Time { return time.Unix(ms/1000, ms%1000*int64(time.Millisecond)).UTC() }
+
+// idScan decode the src of a sql.Scanner holding an ID as text, or as raw
+// bytes when raw is not nil
+func idScan(src interface{}, raw []byte, text encoding.TextUnmarshaler) error {
+	switch src := src.(type) {
+	case nil:
+		return text.UnmarshalText(nil)
+	case string:
+		return text.UnmarshalText([]byte(src))
+	case []byte:
+		if raw != nil && len(src) == len(raw) {
+			copy(raw, src)
+			return nil
+		}
+		return text.UnmarshalText(src)
+	}
+	return fmt.Errorf("kitgo: %w: cannot scan %T", ErrInvalidID, src)
+}
+
+const idCrockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var idCrockfordIndex = func() (index [256]int8) {
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(idCrockford); i++ {
+		index[idCrockford[i]], index[strings.ToLower(idCrockford)[i]] = int8(i), int8(i)
+	}
+	index['I'], index['i'], index['L'], index['l'], index['O'], index['o'] = 1, 1, 1, 1, 0, 0
+	return index
+}()
+
+// ULID is a 128 bits lexicographically sortable identifier, a 48 bits
+// millisecond timestamp followed by 80 bits of entropy, see
+// https://github.com/ulid/spec
+type ULID [16]byte
+
+// String return the 26 characters Crockford's base32 form
+func (u ULID) String() string {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	b := make([]byte, 26)
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = idCrockford[lo&31]
+		hi, lo = hi>>5, lo>>5|hi<<59
+	}
+	return string(b)
+}
+
+// Time return the timestamp of u
+func (u ULID) Time() time.Time {
+	return idTime(int64(binary.BigEndian.Uint64(u[:8]) >> 16))
+}
+
+// Entropy return the 80 bits following the timestamp
+func (u ULID) Entropy() []byte { return append([]byte{}, u[6:]...) }
+
+// MarshalText implement text marshaler, also used for JSON
+func (u ULID) MarshalText() ([]byte, error) {
+	var _ encoding.TextMarshaler = u
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implement text unmarshaler, empty text is the zero ULID
+func (u *ULID) UnmarshalText(b []byte) (err error) {
+	var _ encoding.TextUnmarshaler = u
+	if len(b) == 0 {
+		*u = ULID{}
+		return nil
+	}
+	*u, err = ID.ParseULID(string(b))
+	return err
+}
+
+// Scan implement sql scanner from the text or the 16 raw bytes
+func (u *ULID) Scan(src interface{}) error {
+	var _ sql.Scanner = u
+	return idScan(src, u[:], u)
+}
+
+// Value implement driver valuer as text
+func (u ULID) Value() (driver.Value, error) {
+	var _ driver.Valuer = u
+	return u.String(), nil
+}
+
+// UUID is a 128 bits identifier as defined by RFC 9562, version 7 UUID are
+// sortable by their millisecond timestamp
+type UUID [16]byte
+
+// String return the hyphenated lowercase form
+func (u UUID) String() string {
+	b := make([]byte, 36)
+	hex.Encode(b, u[:4])
+	hex.Encode(b[9:], u[4:6])
+	hex.Encode(b[14:], u[6:8])
+	hex.Encode(b[19:], u[8:10])
+	hex.Encode(b[24:], u[10:])
+	b[8], b[13], b[18], b[23] = '-', '-', '-', '-'
+	return string(b)
+}
+
+// Version return the version of u, e.g. 4 or 7
+func (u UUID) Version() int { return int(u[6] >> 4) }
+
+// Time return the timestamp of a version 7 UUID, or the zero time otherwise
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+	return idTime(int64(binary.BigEndian.Uint64(u[:8]) >> 16))
+}
+
+// MarshalText implement text marshaler, also used for JSON
+func (u UUID) MarshalText() ([]byte, error) {
+	var _ encoding.TextMarshaler = u
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implement text unmarshaler, empty text is the zero UUID
+func (u *UUID) UnmarshalText(b []byte) (err error) {
+	var _ encoding.TextUnmarshaler = u
+	if len(b) == 0 {
+		*u = UUID{}
+		return nil
+	}
+	*u, err = ID.ParseUUID(string(b))
+	return err
+}
+
+// Scan implement sql scanner from the text or the 16 raw bytes
+func (u *UUID) Scan(src interface{}) error {
+	var _ sql.Scanner = u
+	return idScan(src, u[:], u)
+}
+
+// Value implement driver valuer as text
+func (u UUID) Value() (driver.Value, error) {
+	var _ driver.Valuer = u
+	return u.String(), nil
+}
+
+// Snowflake is a 64 bits sortable identifier, see IDGenerator.Snowflake,
+// its JSON form is a string since it does not fit into a float64
+type Snowflake int64
+
+// String return the decimal form
+func (s Snowflake) String() string { return strconv.FormatInt(int64(s), 10) }
+
+// MarshalText implement text marshaler, also used for JSON
+func (s Snowflake) MarshalText() ([]byte, error) {
+	var _ encoding.TextMarshaler = s
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implement text unmarshaler, empty text is zero
+func (s *Snowflake) UnmarshalText(b []byte) (err error) {
+	var _ encoding.TextUnmarshaler = s
+	if len(b) == 0 {
+		*s = 0
+		return nil
+	}
+	*s, err = ID.ParseSnowflake(string(b))
+	return err
+}
+
+// UnmarshalJSON implement json unmarshaler from a string or a number
+func (s *Snowflake) UnmarshalJSON(b []byte) error {
+	var _ json.Unmarshaler = s
+	if string(b) == "null" {
+		return nil
+	}
+	return s.UnmarshalText([]byte(strings.Trim(string(b), `"`)))
+}
+
+// Scan implement sql scanner from an integer or a text
+func (s *Snowflake) Scan(src interface{}) error {
+	var _ sql.Scanner = s
+	if n, ok := src.(int64); ok {
+		*s = Snowflake(n)
+		return nil
+	}
+	return idScan(src, nil, s)
+}
+
+// Value implement driver valuer as an integer
+func (s Snowflake) Value() (driver.Value, error) {
+	var _ driver.Valuer = s
+	return int64(s), nil
+}
@@ -0,0 +1,219 @@
+package kitgo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_id(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	t0 := time.Date(2021, 6, 1, 12, 0, 0, 123e6, time.UTC)
+	clock := func(ts ...time.Time) func() time.Time {
+		i := 0
+		return func() time.Time {
+			t := ts[i]
+			if i < len(ts)-1 {
+				i++
+			}
+			return t
+		}
+	}
+	fixed := func(b byte) func(int) []byte {
+		return func(n int) []byte { return bytes.Repeat([]byte{b}, n) }
+	}
+
+	t.Run("ULID", func(t *testing.T) {
+		gen := kitgo.ID.New(&kitgo.IDConfig{Now: clock(t0, t0, t0.Add(-time.Second)), Entropy: fixed(0)})
+		a, b, c := gen.ULID(), gen.ULID(), gen.ULID()
+		Expect(a.String()).To(Equal("01F73Q3RKV0000000000000000"))
+		Expect(b.String()).To(Equal("01F73Q3RKV0000000000000001"))
+		Expect(c.String()).To(Equal("01F73Q3RKV0000000000000002"))
+		Expect(a.Time()).To(Equal(t0))
+		Expect(c.Time()).To(Equal(t0))
+		Expect(b.Entropy()).To(Equal([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1}))
+
+		// the counter overflow into the next millisecond
+		gen = kitgo.ID.New(&kitgo.IDConfig{Now: clock(t0), Entropy: fixed(0xff)})
+		a, b = gen.ULID(), gen.ULID()
+		Expect(a.String()).To(Equal("01F73Q3RKVZZZZZZZZZZZZZZZZ"))
+		Expect(b.String()).To(Equal("01F73Q3RKWZZZZZZZZZZZZZZZZ"))
+		Expect(b.Time()).To(Equal(t0.Add(time.Millisecond)))
+
+		u, err := kitgo.ID.ParseULID("01f73q3rkwzzzzzzzzzzzzzzzz")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u).To(Equal(b))
+		u, err = kitgo.ID.ParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZO")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.String()).To(Equal("7ZZZZZZZZZZZZZZZZZZZZZZZZ0"))
+		for _, s := range []string{"", "01F73Q3RKV", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01F73Q3RKVZZZZZZZZZZZZZZZU"} {
+			_, err := kitgo.ID.ParseULID(s)
+			Expect(errors.Is(err, kitgo.ErrInvalidID)).To(BeTrue(), s)
+		}
+	})
+	t.Run("UUID", func(t *testing.T) {
+		gen := kitgo.ID.New(&kitgo.IDConfig{Now: clock(t0), Entropy: fixed(0xff)})
+		a, b := gen.UUIDv7(), gen.UUIDv7()
+		Expect(a.String()).To(Equal("0179c771-e27b-77ff-bfff-ffffffffffff"))
+		Expect(b.String()).To(Equal("0179c771-e27b-7800-8000-000000000000"))
+		Expect(a.Version()).To(Equal(7))
+		Expect(b.Time()).To(Equal(t0))
+
+		v4 := gen.UUIDv4()
+		Expect(v4.String()).To(Equal("ffffffff-ffff-4fff-bfff-ffffffffffff"))
+		Expect(v4.Version()).To(Equal(4))
+		Expect(v4.Time()).To(BeZero())
+		Expect(kitgo.ID.UUIDv4()).NotTo(Equal(kitgo.ID.UUIDv4()))
+
+		for _, s := range []string{"0179C771-E27B-7800-8000-000000000000", "0179c771e27b78008000000000000000"} {
+			u, err := kitgo.ID.ParseUUID(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u).To(Equal(b))
+		}
+		for _, s := range []string{"", "0179c771-e27b-7800-8000", "0179c771+e27b-7800-8000-000000000000", "0179c771-e27b-7800-8000-00000000000x"} {
+			_, err := kitgo.ID.ParseUUID(s)
+			Expect(errors.Is(err, kitgo.ErrInvalidID)).To(BeTrue(), s)
+		}
+	})
+	t.Run("Snowflake", func(t *testing.T) {
+		epoch := t0.Add(-time.Second)
+		gen := kitgo.ID.New(&kitgo.IDConfig{Node: 5, Epoch: epoch, Now: clock(t0)})
+		flake := func(gen *kitgo.IDGenerator) kitgo.Snowflake {
+			s, err := gen.Snowflake()
+			Expect(err).NotTo(HaveOccurred())
+			return s
+		}
+		a, b := flake(gen), flake(gen)
+		Expect(a).To(Equal(kitgo.Snowflake(1000<<22 | 5<<12)))
+		Expect(b).To(Equal(a + 1))
+		Expect(gen.Parts(b)).To(Equal(kitgo.SnowflakeParts{Time: t0, Node: 5, Sequence: 1}))
+
+		// a single bit of sequence overflow into the next millisecond
+		gen = kitgo.ID.New(&kitgo.IDConfig{Node: 1<<21 - 1, NodeBits: 21, Epoch: epoch, Now: clock(t0)})
+		a, b, c := flake(gen), flake(gen), flake(gen)
+		Expect(gen.Parts(a)).To(Equal(kitgo.SnowflakeParts{Time: t0, Node: 1<<21 - 1, Sequence: 0}))
+		Expect(gen.Parts(b)).To(Equal(kitgo.SnowflakeParts{Time: t0, Node: 1<<21 - 1, Sequence: 1}))
+		Expect(gen.Parts(c)).To(Equal(kitgo.SnowflakeParts{Time: t0.Add(time.Millisecond), Node: 1<<21 - 1, Sequence: 0}))
+
+		// a clock before the epoch
+		gen = kitgo.ID.New(&kitgo.IDConfig{Epoch: t0.Add(time.Millisecond), Now: clock(t0)})
+		_, err := gen.Snowflake()
+		Expect(errors.Is(err, kitgo.ErrIDEpoch)).To(BeTrue())
+		Expect(err).To(MatchError("kitgo: clock is before the id epoch: " + t0.UTC().Format(time.RFC3339Nano) + " before " + t0.Add(time.Millisecond).UTC().Format(time.RFC3339Nano)))
+
+		s, err := kitgo.ID.ParseSnowflake(c.String())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(c))
+		for _, s := range []string{"", "-1", "x", "9223372036854775808"} {
+			_, err := kitgo.ID.ParseSnowflake(s)
+			Expect(errors.Is(err, kitgo.ErrInvalidID)).To(BeTrue(), s)
+		}
+
+		Expect(func() { kitgo.ID.New(&kitgo.IDConfig{NodeBits: 22}) }).To(Panic())
+		Expect(func() { kitgo.ID.New(&kitgo.IDConfig{Node: 1 << 10}) }).To(Panic())
+		Expect(func() { kitgo.ID.New(&kitgo.IDConfig{Node: -1}) }).To(Panic())
+	})
+	t.Run("JSON", func(t *testing.T) {
+		type row struct {
+			ULID      kitgo.ULID
+			UUID      kitgo.UUID
+			Snowflake kitgo.Snowflake
+		}
+		flake, err := kitgo.ID.New(nil).Snowflake()
+		Expect(err).NotTo(HaveOccurred())
+		in := row{kitgo.ID.ULID(), kitgo.ID.UUID(), flake}
+		b, err := json.Marshal(in)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"ULID":"` + in.ULID.String() + `","UUID":"` + in.UUID.String() + `","Snowflake":"` + in.Snowflake.String() + `"}`))
+		var out row
+		Expect(json.Unmarshal(b, &out)).To(Succeed())
+		Expect(out).To(Equal(in))
+
+		Expect(json.Unmarshal([]byte(`{"ULID":"","UUID":"","Snowflake":42}`), &out)).To(Succeed())
+		Expect(out).To(Equal(row{Snowflake: 42}))
+		Expect(json.Unmarshal([]byte(`{"Snowflake":null}`), &out)).To(Succeed())
+		Expect(out.Snowflake).To(Equal(kitgo.Snowflake(42)))
+		Expect(json.Unmarshal([]byte(`{"Snowflake":""}`), &out)).To(Succeed())
+		Expect(out.Snowflake).To(BeZero())
+		Expect(json.Unmarshal([]byte(`{"ULID":"x"}`), &out)).NotTo(Succeed())
+		Expect(json.Unmarshal([]byte(`{"UUID":"x"}`), &out)).NotTo(Succeed())
+		Expect(json.Unmarshal([]byte(`{"Snowflake":1.5}`), &out)).NotTo(Succeed())
+	})
+	t.Run("SQL", func(t *testing.T) {
+		u, v := kitgo.ID.ULID(), kitgo.ID.UUID()
+		var su kitgo.ULID
+		var sv kitgo.UUID
+		var ss kitgo.Snowflake
+
+		value, err := u.Value()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(su.Scan(value)).To(Succeed())
+		Expect(su).To(Equal(u))
+		Expect(su.Scan(nil)).To(Succeed())
+		Expect(su).To(BeZero())
+		Expect(su.Scan(u[:])).To(Succeed())
+		Expect(su).To(Equal(u))
+		Expect(su.Scan([]byte(u.String()))).To(Succeed())
+		Expect(su).To(Equal(u))
+
+		value, err = v.Value()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sv.Scan(value)).To(Succeed())
+		Expect(sv).To(Equal(v))
+		Expect(sv.Scan(v[:])).To(Succeed())
+		Expect(sv).To(Equal(v))
+
+		value, err = kitgo.Snowflake(42).Value()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ss.Scan(value)).To(Succeed())
+		Expect(ss).To(Equal(kitgo.Snowflake(42)))
+		Expect(ss.Scan([]byte("43"))).To(Succeed())
+		Expect(ss).To(Equal(kitgo.Snowflake(43)))
+
+		Expect(errors.Is(su.Scan(1.5), kitgo.ErrInvalidID)).To(BeTrue())
+		Expect(errors.Is(sv.Scan("x"), kitgo.ErrInvalidID)).To(BeTrue())
+		Expect(errors.Is(ss.Scan(true), kitgo.ErrInvalidID)).To(BeTrue())
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		const workers, n = 8, 1000
+		gen := kitgo.ID.New(nil)
+		ulids, uuids, flakes := make([][]string, workers), make([][]string, workers), make([][]int64, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					ulids[w] = append(ulids[w], gen.ULID().String())
+					uuids[w] = append(uuids[w], gen.UUIDv7().String())
+					s, _ := gen.Snowflake()
+					flakes[w] = append(flakes[w], int64(s))
+				}
+			}(w)
+		}
+		wg.Wait()
+		seen := map[string]bool{}
+		for w := 0; w < workers; w++ {
+			// each worker observe increasing IDs
+			Expect(sort.StringsAreSorted(ulids[w])).To(BeTrue())
+			Expect(sort.StringsAreSorted(uuids[w])).To(BeTrue())
+			Expect(sort.SliceIsSorted(flakes[w], func(i, j int) bool { return flakes[w][i] < flakes[w][j] })).To(BeTrue())
+			for i := 0; i < n; i++ {
+				seen[ulids[w][i]], seen[uuids[w][i]] = true, true
+				seen[kitgo.Snowflake(flakes[w][i]).String()] = true
+			}
+		}
+		Expect(seen).To(HaveLen(3 * workers * n))
+		Expect(strings.Compare(kitgo.ID.ULID().String(), kitgo.ID.ULID().String())).To(Equal(-1))
+	})
+}
@@ -0,0 +1,236 @@
+package kitgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var Lifecycle lifecycle_
+
+type lifecycle_ struct{}
+
+// New return a lifecycle manager, conf could be nil to use the default value
+func (lifecycle_) New(conf *LifecycleConfig) *LifecycleWrapper {
+	x := &LifecycleWrapper{}
+	if conf != nil {
+		x.conf = *conf
+	}
+	if x.conf.Signals == nil {
+		x.conf.Signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT}
+	}
+	if x.conf.Timeout <= 0 {
+		x.conf.Timeout = 5 * time.Second
+	}
+	if x.conf.OnInfo == nil {
+		x.conf.OnInfo = func(string) {}
+	}
+	return x
+}
+
+type LifecycleConfig struct {
+	// Signals trigger the shutdown, default to SIGTERM, SIGINT and SIGQUIT,
+	// set to an empty slice to rely on the context only
+	Signals []os.Signal
+
+	// Timeout is the default Timeout of a component, default to 5s
+	Timeout time.Duration
+
+	// OnInfo report the progress of Run
+	OnInfo func(string)
+}
+
+// LifecycleComponent is a named part of an application, every hook is optional
+//
+// Start is called in dependency order, Run is then called in its own goroutine
+// until the lifecycle stops, and Stop is called in reverse dependency order,
+// Timeout bound each Start, Stop and the return of Run after Stop
+type LifecycleComponent struct {
+	Name      string
+	DependsOn []string
+	Timeout   time.Duration
+	Start     func(ctx context.Context) error
+	Run       func(ctx context.Context) error
+	Stop      func(ctx context.Context) error
+}
+
+type LifecycleWrapper struct {
+	conf       LifecycleConfig
+	mu         sync.Mutex
+	components []LifecycleComponent
+}
+
+// Register add components, the name should be unique and non-empty
+func (x *LifecycleWrapper) Register(components ...LifecycleComponent) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, c := range components {
+		if c.Name == "" {
+			return errors.New("kitgo: lifecycle component without name")
+		}
+		for i := range x.components {
+			if x.components[i].Name == c.Name {
+				return fmt.Errorf("kitgo: lifecycle component %q is registered twice", c.Name)
+			}
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = x.conf.Timeout
+		}
+		x.components = append(x.components, c)
+	}
+	return nil
+}
+
+// Run start every component and block until ctx is done, a signal is received
+// or the Run of any component returns, then every started component is
+// stopped, errors of each step are returned as errorList
+//
+// If a component fails to start, or ctx is done or a signal is received while
+// starting, the components that have been started are stopped and Run return
+// immediately
+func (x *LifecycleWrapper) Run(ctx context.Context) error {
+	order, err := x.order()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// signals are handled from the start, so that a signal received while
+	// starting does not kill the process before started components stop
+	if len(x.conf.Signals) > 0 {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, x.conf.Signals...)
+		defer signal.Stop(sigs)
+		go func() {
+			select {
+			case sig := <-sigs:
+				x.conf.OnInfo(fmt.Sprintf("lifecycle: signal %s", sig))
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	var errs errorList
+	var started []LifecycleComponent
+	for _, c := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := c.call(ctx, "start", c.Start); err != nil {
+			errs = errs.Append(err)
+			break
+		}
+		x.conf.OnInfo(fmt.Sprintf("lifecycle: %q started", c.Name))
+		started = append(started, c)
+	}
+
+	runs := make([]chan error, len(started))
+	if len(errs) < 1 && len(started) == len(order) {
+		for i, c := range started {
+			if c.Run == nil {
+				continue
+			}
+			runs[i] = make(chan error, 1)
+			go func(c LifecycleComponent, done chan<- error) {
+				err := c.Run(ctx)
+				if err != nil {
+					err = fmt.Errorf("kitgo: lifecycle %q run: %w", c.Name, err)
+				}
+				done <- err
+				cancel()
+			}(c, runs[i])
+		}
+		<-ctx.Done()
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		errs = errs.Append(c.call(context.Background(), "stop", c.Stop))
+		if runs[i] != nil {
+			t := time.NewTimer(c.Timeout)
+			select {
+			case err := <-runs[i]:
+				errs = errs.Append(err)
+			case <-t.C:
+				errs = errs.Append(fmt.Errorf("kitgo: lifecycle %q run: %w", c.Name, context.DeadlineExceeded))
+			}
+			t.Stop()
+		}
+		x.conf.OnInfo(fmt.Sprintf("lifecycle: %q stopped", c.Name))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// order sort components so that each one comes after its dependencies, the
+// registration order is kept otherwise
+func (x *LifecycleWrapper) order() ([]LifecycleComponent, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	index := make(map[string]int, len(x.components))
+	for i, c := range x.components {
+		index[c.Name] = i
+	}
+	const visiting, visited = 1, 2
+	state := make([]int, len(x.components))
+	order := make([]LifecycleComponent, 0, len(x.components))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		c := x.components[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("kitgo: lifecycle dependency cycle %q", append(path, c.Name))
+		}
+		state[i] = visiting
+		for _, dep := range c.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return fmt.Errorf("kitgo: lifecycle %q depends on unknown %q", c.Name, dep)
+			}
+			if err := visit(j, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, c)
+		return nil
+	}
+	for i := range x.components {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// call fn bounded by c.Timeout, fn is abandoned when it does not return in time
+func (c LifecycleComponent) call(ctx context.Context, phase string, fn func(context.Context) error) error {
+	if fn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("kitgo: lifecycle %q %s: %w", c.Name, phase, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package kitgo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_lifecycle(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+
+	type recorder struct {
+		sync.Mutex
+		events []string
+	}
+	record := func(r *recorder, event string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			r.Lock()
+			defer r.Unlock()
+			r.events = append(r.events, event)
+			return err
+		}
+	}
+	component := func(r *recorder, name string, deps ...string) kitgo.LifecycleComponent {
+		return kitgo.LifecycleComponent{
+			Name:      name,
+			DependsOn: deps,
+			Start:     record(r, "start "+name, nil),
+			Stop:      record(r, "stop "+name, nil),
+		}
+	}
+	noSignal := &kitgo.LifecycleConfig{Signals: []os.Signal{}}
+
+	t.Run("order", func(t *testing.T) {
+		r := new(recorder)
+		var infos []string
+		x := kitgo.Lifecycle.New(&kitgo.LifecycleConfig{
+			Signals: []os.Signal{},
+			OnInfo:  func(s string) { infos = append(infos, s) },
+		})
+		Expect(x.Register(
+			component(r, "http", "sql", "cache"),
+			component(r, "cache", "redis"),
+			component(r, "sql"),
+			component(r, "redis"),
+			kitgo.LifecycleComponent{Name: "noop"},
+		)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		Expect(x.Run(ctx)).To(Succeed())
+		Expect(r.events).To(Equal([]string{
+			"start sql", "start redis", "start cache", "start http",
+			"stop http", "stop cache", "stop redis", "stop sql",
+		}))
+		Expect(infos).To(HaveLen(10))
+		Expect(infos[0]).To(Equal(`lifecycle: "sql" started`))
+		Expect(infos[9]).To(Equal(`lifecycle: "sql" stopped`))
+	})
+	t.Run("register", func(t *testing.T) {
+		x := kitgo.Lifecycle.New(nil)
+		Expect(x.Register(kitgo.LifecycleComponent{})).To(MatchError("kitgo: lifecycle component without name"))
+		Expect(x.Register(kitgo.LifecycleComponent{Name: "a"}, kitgo.LifecycleComponent{Name: "a"})).
+			To(MatchError(`kitgo: lifecycle component "a" is registered twice`))
+
+		x = kitgo.Lifecycle.New(nil)
+		Expect(x.Register(kitgo.LifecycleComponent{Name: "a", DependsOn: []string{"b"}})).To(Succeed())
+		Expect(x.Run(ctx)).To(MatchError(`kitgo: lifecycle "a" depends on unknown "b"`))
+
+		x = kitgo.Lifecycle.New(nil)
+		Expect(x.Register(
+			kitgo.LifecycleComponent{Name: "a", DependsOn: []string{"b"}},
+			kitgo.LifecycleComponent{Name: "b", DependsOn: []string{"c"}},
+			kitgo.LifecycleComponent{Name: "c", DependsOn: []string{"a"}},
+		)).To(Succeed())
+		Expect(x.Run(ctx)).To(MatchError(`kitgo: lifecycle dependency cycle ["a" "b" "c" "a"]`))
+	})
+	t.Run("start failure", func(t *testing.T) {
+		r := new(recorder)
+		x := kitgo.Lifecycle.New(noSignal)
+		failing := component(r, "b", "a")
+		failing.Start = record(r, "start b", errBoom)
+		Expect(x.Register(component(r, "a"), failing, component(r, "c", "b"))).To(Succeed())
+
+		err := x.Run(ctx)
+		Expect(errors.Is(err, errBoom)).To(BeTrue())
+		Expect(err.Error()).To(Equal(`kitgo: lifecycle "b" start: boom`))
+		Expect(r.events).To(Equal([]string{"start a", "start b", "stop a"}))
+	})
+	t.Run("stop errors and timeout", func(t *testing.T) {
+		r := new(recorder)
+		x := kitgo.Lifecycle.New(noSignal)
+		a, b := component(r, "a"), component(r, "b")
+		a.Stop = record(r, "stop a", errBoom)
+		b.Timeout = time.Millisecond
+		b.Stop = func(ctx context.Context) error { <-ctx.Done(); return nil }
+		b.Run = func(context.Context) error { select {} }
+		Expect(x.Register(a, b)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		err := x.Run(ctx)
+		Expect(errors.Is(err, errBoom)).To(BeTrue())
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(err.Error()).To(Equal(fmt.Sprintf("%s\n%s\n%s",
+			`kitgo: lifecycle "b" stop: context deadline exceeded`,
+			`kitgo: lifecycle "b" run: context deadline exceeded`,
+			`kitgo: lifecycle "a" stop: boom`,
+		)))
+	})
+	t.Run("run returns", func(t *testing.T) {
+		r := new(recorder)
+		x := kitgo.Lifecycle.New(noSignal)
+		a, b := component(r, "a"), component(r, "b", "a")
+		a.Run = func(ctx context.Context) error { <-ctx.Done(); return nil }
+		b.Run = func(context.Context) error { return errBoom }
+		Expect(x.Register(a, b)).To(Succeed())
+
+		err := x.Run(ctx)
+		Expect(err).To(MatchError(`kitgo: lifecycle "b" run: boom`))
+		Expect(r.events).To(Equal([]string{"start a", "start b", "stop b", "stop a"}))
+	})
+	t.Run("signal", func(t *testing.T) {
+		var mu sync.Mutex
+		var infos []string
+		x := kitgo.Lifecycle.New(&kitgo.LifecycleConfig{
+			Signals: []os.Signal{syscall.SIGUSR2},
+			OnInfo:  func(s string) { mu.Lock(); infos = append(infos, s); mu.Unlock() },
+		})
+		started := make(chan struct{})
+		Expect(x.Register(kitgo.LifecycleComponent{Name: "a", Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return nil
+		}})).To(Succeed())
+		go func() {
+			<-started
+			for i := 0; i < 100; i++ {
+				mu.Lock()
+				n := len(infos)
+				mu.Unlock()
+				if n > 1 {
+					return
+				}
+				_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		Expect(x.Run(ctx)).To(Succeed())
+		Expect(infos).To(ContainElement("lifecycle: signal user defined signal 2"))
+	})
+	t.Run("signal while starting", func(t *testing.T) {
+		r := new(recorder)
+		var mu sync.Mutex
+		var infos []string
+		x := kitgo.Lifecycle.New(&kitgo.LifecycleConfig{
+			Signals: []os.Signal{syscall.SIGUSR2},
+			OnInfo:  func(s string) { mu.Lock(); infos = append(infos, s); mu.Unlock() },
+		})
+		b := component(r, "b", "a")
+		b.Start = func(ctx context.Context) error {
+			_ = record(r, "start b", nil)(ctx)
+			_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+			<-ctx.Done()
+			return nil
+		}
+		Expect(x.Register(component(r, "a"), b, component(r, "c", "b"))).To(Succeed())
+
+		err := x.Run(ctx)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(r.events).To(Equal([]string{"start a", "start b", "stop a"}))
+		Expect(infos).To(ContainElement("lifecycle: signal user defined signal 2"))
+
+		// ctx done while starting
+		r = new(recorder)
+		cctx, cancel := context.WithCancel(ctx)
+		x = kitgo.Lifecycle.New(&kitgo.LifecycleConfig{Signals: []os.Signal{}, OnInfo: func(string) { cancel() }})
+		Expect(x.Register(component(r, "a"), component(r, "b", "a"))).To(Succeed())
+		Expect(x.Run(cctx)).To(Succeed())
+		Expect(r.events).To(Equal([]string{"start a", "stop a"}))
+	})
+	t.Run("http server", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := l.Addr().String()
+		Expect(l.Close()).To(Succeed())
+
+		srv := kitgo.HTTP.Server.New()
+		srv.Addr = addr
+		srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+		var info string
+		c := srv.Component("http", func(s string) { info = s })
+
+		x := kitgo.Lifecycle.New(noSignal)
+		Expect(x.Register(c, kitgo.LifecycleComponent{Name: "probe", DependsOn: []string{"http"}, Run: func(ctx context.Context) error {
+			for {
+				res, err := http.Get("http://" + addr)
+				if err == nil {
+					res.Body.Close()
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}})).To(Succeed())
+		Expect(x.Run(ctx)).To(Succeed())
+		Expect(info).To(Equal("srv running on http://0.0.0.0" + addr))
+
+		// listen failure is reported by Run
+		x = kitgo.Lifecycle.New(noSignal)
+		srv = kitgo.HTTP.Server.New()
+		srv.Addr = "127.0.0.1:-1"
+		Expect(x.Register(srv.Component("http", nil))).To(Succeed())
+		Expect(x.Run(ctx)).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,278 @@
+package kitgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var MIME mime_
+
+type mime_ struct{}
+
+// TypeByExtension return the media type of ext, which could be a file name or
+// an extension with or without the leading dot, the std `mime` package is used
+// as fallback, an empty string is returned when the type is unknown
+func (mime_) TypeByExtension(ext string) string {
+	ext = mimeExtension(ext)
+	mimeRegistry.RLock()
+	typ := mimeRegistry.types[ext]
+	mimeRegistry.RUnlock()
+	if typ == "" && ext != "" {
+		typ, _, _ = mime.ParseMediaType(mime.TypeByExtension(ext))
+	}
+	return typ
+}
+
+// ExtensionsByType return the registered extensions of typ in the order of
+// registration, the parameters of typ are ignored
+func (mime_) ExtensionsByType(typ string) []string {
+	mimeRegistry.RLock()
+	defer mimeRegistry.RUnlock()
+	return append([]string(nil), mimeRegistry.exts[mimeMediaType(typ)]...)
+}
+
+// Detect return the media type of content by checking the magic bytes of the
+// registered detectors, then fallback to http.DetectContentType, so the
+// formats unknown to the latter (webp, woff2, 7z, docx, etc.) are detected,
+// the archive based formats are matched by the file names of the first
+// entries, so a few KB of content should be given
+func (mime_) Detect(content []byte) string {
+	mimeRegistry.RLock()
+	defer mimeRegistry.RUnlock()
+	for i := len(mimeRegistry.magic) - 1; i >= 0; i-- {
+		if m := mimeRegistry.magic[i]; m.match(content) {
+			return m.typ
+		}
+	}
+	return http.DetectContentType(content)
+}
+
+// Register map each of exts to typ, an extension that has been registered
+// is moved to typ
+func (mime_) Register(typ string, exts ...string) {
+	typ = mimeMediaType(typ)
+	mimeRegistry.Lock()
+	defer mimeRegistry.Unlock()
+	for _, ext := range exts {
+		if ext = mimeExtension(ext); ext == "" || typ == "" {
+			continue
+		}
+		if old, ok := mimeRegistry.types[ext]; ok {
+			list := mimeRegistry.exts[old][:0]
+			for _, e := range mimeRegistry.exts[old] {
+				if e != ext {
+					list = append(list, e)
+				}
+			}
+			mimeRegistry.exts[old] = list
+		}
+		mimeRegistry.types[ext] = typ
+		mimeRegistry.exts[typ] = append(mimeRegistry.exts[typ], ext)
+	}
+}
+
+// RegisterMagic add a detector used by Detect, the detectors are checked in
+// the reverse order of registration, so a custom detector take precedence
+// over the builtin ones
+func (mime_) RegisterMagic(typ string, match func(content []byte) bool) {
+	if match == nil {
+		return
+	}
+	mimeRegistry.Lock()
+	defer mimeRegistry.Unlock()
+	mimeRegistry.magic = append(mimeRegistry.magic, mimeMagic{typ, match})
+}
+
+type mimeMagic struct {
+	typ   string
+	match func([]byte) bool
+}
+
+var mimeRegistry = struct {
+	sync.RWMutex
+	types map[string]string
+	exts  map[string][]string
+	magic []mimeMagic
+}{
+	types: map[string]string{},
+	exts:  map[string][]string{},
+}
+
+func init() {
+	for _, t := range mimeTypes {
+		MIME.Register(t[1], t[0])
+	}
+	zip := mimeAt(0, "PK\x03\x04")
+	// an entry name follows each local file header, the signature and 26 bytes
+	// whose last 4 are the little-endian length of the name & extra field
+	zipEntry := func(prefix string) func([]byte) bool {
+		return mimeAll(zip, func(b []byte) bool {
+			for i := 0; ; i += 4 {
+				j := bytes.Index(b[i:], []byte("PK\x03\x04"))
+				if j < 0 || i+j+30 > len(b) {
+					return false
+				}
+				i += j
+				name := b[i+30:]
+				if n := int(binary.LittleEndian.Uint16(b[i+26:])); len(name) > n {
+					name = name[:n]
+				}
+				if bytes.HasPrefix(name, []byte(prefix)) {
+					return true
+				}
+			}
+		})
+	}
+	// the first entry of epub & opendocument is an uncompressed "mimetype"
+	zipMimetype := func(typ string) func([]byte) bool {
+		return mimeAll(zip, mimeAt(30, "mimetype"+typ))
+	}
+	for _, m := range []mimeMagic{
+		{"application/zip", zip},
+		{"application/java-archive", zipEntry("META-INF/MANIFEST.MF")},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", zipEntry("word/")},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", zipEntry("xl/")},
+		{"application/vnd.openxmlformats-officedocument.presentationml.presentation", zipEntry("ppt/")},
+		{"application/epub+zip", zipMimetype("application/epub+zip")},
+		{"application/vnd.oasis.opendocument.text", zipMimetype("application/vnd.oasis.opendocument.text")},
+		{"application/vnd.oasis.opendocument.spreadsheet", zipMimetype("application/vnd.oasis.opendocument.spreadsheet")},
+		{"application/vnd.oasis.opendocument.presentation", zipMimetype("application/vnd.oasis.opendocument.presentation")},
+		{"application/x-7z-compressed", mimeAt(0, "7z\xbc\xaf\x27\x1c")},
+		{"application/x-bzip2", mimeAt(0, "BZh")},
+		{"application/vnd.rar", mimeAt(0, "Rar!\x1a\x07")},
+		{"application/gzip", mimeAt(0, "\x1f\x8b\x08")},
+		{"application/x-tar", mimeAt(257, "ustar")},
+		{"application/vnd.ms-fontobject", mimeAt(34, "LP")},
+		{"font/ttf", mimeAt(0, "\x00\x01\x00\x00\x00")},
+		{"font/otf", mimeAt(0, "OTTO")},
+		{"font/woff", mimeAt(0, "wOFF")},
+		{"font/woff2", mimeAt(0, "wOF2")},
+		{"image/webp", mimeAll(mimeAt(0, "RIFF"), mimeAt(8, "WEBPVP"))},
+		{"image/tiff", mimeAt(0, "II*\x00")},
+		{"image/tiff", mimeAt(0, "MM\x00*")},
+		{"audio/midi", mimeAt(0, "MThd")},
+		{"audio/opus", mimeAll(mimeAt(0, "OggS"), mimeAt(28, "OpusHead"))},
+	} {
+		MIME.RegisterMagic(m.typ, m.match)
+	}
+}
+
+func mimeExtension(ext string) string {
+	if i := strings.LastIndexByte(ext, '.'); i > 0 {
+		ext = ext[i:]
+	} else if i < 0 && ext != "" {
+		ext = "." + ext
+	}
+	if ext = strings.ToLower(ext); filepath.Base(ext) != ext || ext == "." {
+		return ""
+	}
+	return ext
+}
+
+func mimeMediaType(typ string) string {
+	if i := strings.IndexByte(typ, ';'); i >= 0 {
+		typ = typ[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(typ))
+}
+
+func mimeAt(offset int, magic string) func([]byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= offset+len(magic) && string(b[offset:offset+len(magic)]) == magic
+	}
+}
+
+func mimeAll(matches ...func([]byte) bool) func([]byte) bool {
+	return func(b []byte) bool {
+		for _, match := range matches {
+			if !match(b) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// mimeTypes is taken from
+// https://developer.mozilla.org/en-US/docs/Web/HTTP/Basics_of_HTTP/MIME_types/Common_types
+var mimeTypes = [][2]string{
+	{".aac", "audio/aac"},
+	{".abw", "application/x-abiword"},
+	{".arc", "application/x-freearc"},
+	{".avi", "video/x-msvideo"},
+	{".azw", "application/vnd.amazon.ebook"},
+	{".bin", "application/octet-stream"},
+	{".bmp", "image/bmp"},
+	{".bz", "application/x-bzip"},
+	{".bz2", "application/x-bzip2"},
+	{".csh", "application/x-csh"},
+	{".css", "text/css"},
+	{".csv", "text/csv"},
+	{".doc", "application/msword"},
+	{".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	{".eot", "application/vnd.ms-fontobject"},
+	{".epub", "application/epub+zip"},
+	{".gz", "application/gzip"},
+	{".gif", "image/gif"},
+	{".htm", "text/html"},
+	{".html", "text/html"},
+	{".ico", "image/vnd.microsoft.icon"},
+	{".ics", "text/calendar"},
+	{".jar", "application/java-archive"},
+	{".jpeg", "image/jpeg"},
+	{".jpg", "image/jpeg"},
+	{".js", "text/javascript"},
+	{".json", "application/json"},
+	{".jsonld", "application/ld+json"},
+	{".mid", "audio/midi"},
+	{".midi", "audio/midi"},
+	{".mjs", "text/javascript"},
+	{".mp3", "audio/mpeg"},
+	{".mpeg", "video/mpeg"},
+	{".mpkg", "application/vnd.apple.installer+xml"},
+	{".odp", "application/vnd.oasis.opendocument.presentation"},
+	{".ods", "application/vnd.oasis.opendocument.spreadsheet"},
+	{".odt", "application/vnd.oasis.opendocument.text"},
+	{".oga", "audio/ogg"},
+	{".ogv", "video/ogg"},
+	{".ogx", "application/ogg"},
+	{".opus", "audio/opus"},
+	{".otf", "font/otf"},
+	{".png", "image/png"},
+	{".pdf", "application/pdf"},
+	{".php", "application/x-httpd-php"},
+	{".ppt", "application/vnd.ms-powerpoint"},
+	{".pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	{".rar", "application/vnd.rar"},
+	{".rtf", "application/rtf"},
+	{".sh", "application/x-sh"},
+	{".svg", "image/svg+xml"},
+	{".swf", "application/x-shockwave-flash"},
+	{".tar", "application/x-tar"},
+	{".tif", "image/tiff"},
+	{".tiff", "image/tiff"},
+	{".ts", "video/mp2t"},
+	{".ttf", "font/ttf"},
+	{".txt", "text/plain"},
+	{".vsd", "application/vnd.visio"},
+	{".wav", "audio/wav"},
+	{".weba", "audio/webm"},
+	{".webm", "video/webm"},
+	{".webp", "image/webp"},
+	{".woff", "font/woff"},
+	{".woff2", "font/woff2"},
+	{".xhtml", "application/xhtml+xml"},
+	{".xls", "application/vnd.ms-excel"},
+	{".xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	{".xml", "text/xml"},
+	{".xul", "application/vnd.mozilla.xul+xml"},
+	{".zip", "application/zip"},
+	{".3gp", "video/3gpp"},
+	{".3g2", "video/3gpp2"},
+	{".7z", "application/x-7z-compressed"},
+}
@@ -0,0 +1,129 @@
+package kitgo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_mime(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	t.Run("TypeByExtension", func(t *testing.T) {
+		for ext, typ := range map[string]string{
+			".docx":            "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			".WOFF2":           "font/woff2",
+			"webp":             "image/webp",
+			"archive.tar.7z":   "application/x-7z-compressed",
+			"/static/main.mjs": "text/javascript",
+			".wasm":            "application/wasm",
+			".unknown-ext":     "",
+			"":                 "",
+			".":                "",
+			"dir.d/file":       "",
+		} {
+			Expect(kitgo.MIME.TypeByExtension(ext)).To(Equal(typ), ext)
+		}
+	})
+	t.Run("ExtensionsByType", func(t *testing.T) {
+		Expect(kitgo.MIME.ExtensionsByType("text/html; charset=utf-8")).To(Equal([]string{".htm", ".html"}))
+		Expect(kitgo.MIME.ExtensionsByType("image/TIFF")).To(Equal([]string{".tif", ".tiff"}))
+		Expect(kitgo.MIME.ExtensionsByType("application/x-unknown")).To(BeEmpty())
+	})
+	t.Run("Register", func(t *testing.T) {
+		kitgo.MIME.Register("application/x-kitgo-a", ".kga", "KGB", "", ".")
+		Expect(kitgo.MIME.TypeByExtension("file.kgb")).To(Equal("application/x-kitgo-a"))
+		kitgo.MIME.Register("Application/X-Kitgo-B; charset=utf-8", ".kgb")
+		Expect(kitgo.MIME.TypeByExtension("file.kgb")).To(Equal("application/x-kitgo-b"))
+		Expect(kitgo.MIME.ExtensionsByType("application/x-kitgo-a")).To(Equal([]string{".kga"}))
+		Expect(kitgo.MIME.ExtensionsByType("application/x-kitgo-b")).To(Equal([]string{".kgb"}))
+		kitgo.MIME.Register("", ".kgc")
+		Expect(kitgo.MIME.TypeByExtension(".kgc")).To(BeEmpty())
+
+		kitgo.MIME.RegisterMagic("application/x-kitgo-a", nil)
+		kitgo.MIME.RegisterMagic("application/x-kitgo-a", func(b []byte) bool { return bytes.HasPrefix(b, []byte("KITGO")) })
+		Expect(kitgo.MIME.Detect([]byte("KITGO\x00"))).To(Equal("application/x-kitgo-a"))
+	})
+	t.Run("Detect", func(t *testing.T) {
+		// each entry is a local file header without data
+		zip := func(names ...string) (b []byte) {
+			for _, name := range names {
+				b = append(b, "PK\x03\x04"...)
+				b = append(b, make([]byte, 22)...)
+				b = append(b, byte(len(name)), byte(len(name)>>8), 0, 0)
+				b = append(b, name...)
+			}
+			return b
+		}
+		tar := make([]byte, 512)
+		copy(tar[257:], "ustar")
+		eot := make([]byte, 64)
+		copy(eot[34:], "LP")
+		for typ, content := range map[string][]byte{
+			"application/zip":          zip("hello.txt"),
+			"application/java-archive": zip("META-INF/MANIFEST.MF"),
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   zip("[Content_Types].xml", "_rels/.rels", "word/document.xml"),
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         zip("[Content_Types].xml", "xl/workbook.xml"),
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation": zip("[Content_Types].xml", "ppt/presentation.xml"),
+			"application/epub+zip":                            append(zip("mimetype"), "application/epub+zip"...),
+			"application/vnd.oasis.opendocument.text":         append(zip("mimetype"), "application/vnd.oasis.opendocument.text"...),
+			"application/vnd.oasis.opendocument.spreadsheet":  append(zip("mimetype"), "application/vnd.oasis.opendocument.spreadsheet"...),
+			"application/vnd.oasis.opendocument.presentation": append(zip("mimetype"), "application/vnd.oasis.opendocument.presentation"...),
+			"application/x-7z-compressed":                     []byte("7z\xbc\xaf\x27\x1c\x00\x04"),
+			"application/x-bzip2":                             []byte("BZh91AY&SY"),
+			"application/vnd.rar":                             []byte("Rar!\x1a\x07\x01\x00"),
+			"application/gzip":                                []byte("\x1f\x8b\x08\x00"),
+			"application/x-tar":                               tar,
+			"application/vnd.ms-fontobject":                   eot,
+			"font/ttf":                                        []byte("\x00\x01\x00\x00\x00\x0f"),
+			"font/otf":                                        []byte("OTTO\x00\x0b"),
+			"font/woff":                                       []byte("wOFF\x00\x01"),
+			"font/woff2":                                      []byte("wOF2\x00\x01"),
+			"image/webp":                                      []byte("RIFF\x24\x00\x00\x00WEBPVP8 "),
+			"image/tiff":                                      []byte("MM\x00*\x00\x00\x00\x08"),
+			"audio/midi":                                      []byte("MThd\x00\x00\x00\x06"),
+			"audio/opus":                                      []byte("OggS\x00\x02" + strings.Repeat("\x00", 22) + "OpusHead"),
+			"image/png":                                       []byte("\x89PNG\x0d\x0a\x1a\x0a"),
+			"text/plain; charset=utf-8":                       []byte("hello"),
+		} {
+			Expect(kitgo.MIME.Detect(content)).To(Equal(typ), typ)
+		}
+		// names are only matched right after a local file header
+		Expect(kitgo.MIME.Detect(zip("keyword/document.xml", "axl/workbook.xml"))).To(Equal("application/zip"))
+		Expect(kitgo.MIME.Detect(append(zip("a.txt"), "word/PK\x03\x04"...))).To(Equal("application/zip"))
+		Expect(kitgo.MIME.Detect(append(zip("[Content_Types].xml"), "PK\x03\x04\x00"...))).To(Equal("application/zip"))
+		Expect(kitgo.MIME.Detect([]byte("II*\x00\x08\x00\x00\x00"))).To(Equal("image/tiff"))
+		Expect(kitgo.MIME.Detect([]byte("RIFF\x24\x00\x00\x00WAVEfmt "))).To(Equal("audio/wave"))
+	})
+	t.Run("ServeFile", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, typ := range map[string]string{
+			"font.woff2": "font/woff2",
+			"app.mjs":    "text/javascript; charset=utf-8",
+			"data.json":  "application/json",
+		} {
+			path := filepath.Join(dir, name)
+			Expect(ioutil.WriteFile(path, []byte("{}"), 0644)).To(Succeed())
+			w, r := kitgo.HTTP.Handler.Test("", "/"+name, nil)
+			kitgo.HTTP.Handler.ServeFile(path).ServeHTTP(w, r)
+			Expect(w.Header().Get(kitgo.ContentType)).To(Equal(typ), name)
+		}
+	})
+	t.Run("Minify", func(t *testing.T) {
+		dst := new(bytes.Buffer)
+		Expect(kitgo.Compress.New().Minify.WithMediaType(".js").Write(dst, strings.NewReader("var a = 1 ;"))).To(Succeed())
+		Expect(dst.String()).To(Equal("var a=1"))
+	})
+	t.Run("ResponseWith", func(t *testing.T) {
+		state := kitgo.HTTP.Handler.NewResponseState(200, nil, []byte("wOF2\x00\x01"), "minify")
+		w, r := kitgo.HTTP.Handler.Test("", "/", nil)
+		kitgo.HTTP.Handler.ResponseWith(state).ServeHTTP(w, r)
+		Expect(w.Header().Get(kitgo.ContentType)).To(Equal("font/woff2"))
+	})
+}
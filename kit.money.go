@@ -0,0 +1,193 @@
+package kitgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when operands are not in the same currency
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrMoneyOverflow is returned when the result does not fit into int64 units
+	ErrMoneyOverflow = errors.New("money overflow")
+	// ErrMoneyRatio is returned when a ratio is zero or negative
+	ErrMoneyRatio = errors.New("invalid money ratio")
+	// ErrMoneyCurrency is returned when the currency of a tag cannot be resolved
+	ErrMoneyCurrency = errors.New("invalid money currency")
+)
+
+// Money is an exact amount of currency in integer minor units, e.g. 1050 is
+// "$10.50" for "en-US", the number of minor units per major unit is derived
+// from the region of Tag, the same way Currency.String does
+type Money struct {
+	// Tag parse according to BCP47 string, see Currency.Tag
+	Tag string
+
+	// Units contains the amount in minor units
+	Units int64
+
+	// Format see Currency.Format
+	Format string
+}
+
+// Money convert Value into minor units, rounded half away from zero,
+// ErrMoneyOverflow is returned when Value is NaN, infinite or does not fit
+// into int64 units
+func (c Currency) Money() (Money, error) {
+	m := Money{Tag: c.Tag, Format: c.Format}
+	units := math.Round(c.Value * math.Pow10(moneyScale(c.Tag)))
+	// float64(math.MaxInt64) is 2^63 which is already out of range
+	if !(units >= math.MinInt64 && units < math.MaxInt64) {
+		return m, fmt.Errorf("kitgo: %w: %v", ErrMoneyOverflow, c.Value)
+	}
+	m.Units = int64(units)
+	return m, nil
+}
+
+// Currency convert Money back to Currency, e.g. for formatting
+func (m Money) Currency() Currency {
+	return Currency{m.Tag, float64(m.Units) / math.Pow10(m.Scale()), m.Format}
+}
+
+// Scale return the number of minor unit digits, e.g. 2 for USD, 0 for JPY
+func (m Money) Scale() int { return moneyScale(m.Tag) }
+
+// String implement a stringer interface, see Currency.String
+func (m Money) String() string {
+	var _ fmt.Stringer = m
+	return m.Currency().String()
+}
+
+// MarshalJSON implement json marshaler, see Currency.MarshalJSON
+func (m Money) MarshalJSON() ([]byte, error) {
+	var _ json.Marshaler = m
+	return m.Currency().MarshalJSON()
+}
+
+// Add return m + o
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.same(o); err != nil {
+		return m, err
+	}
+	return m.with(new(big.Int).Add(big.NewInt(m.Units), big.NewInt(o.Units)))
+}
+
+// Sub return m - o
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.same(o); err != nil {
+		return m, err
+	}
+	return m.with(new(big.Int).Sub(big.NewInt(m.Units), big.NewInt(o.Units)))
+}
+
+// Mul return m * num / den, rounded half away from zero to the minor unit,
+// e.g. Mul(11, 100) to add 11% tax
+func (m Money) Mul(num, den int64) (Money, error) {
+	if den == 0 {
+		return m, fmt.Errorf("kitgo: %w: zero denominator", ErrMoneyRatio)
+	}
+	x := new(big.Int).Mul(big.NewInt(m.Units), big.NewInt(num))
+	d := big.NewInt(den)
+	q, r := new(big.Int).QuoRem(x, d, new(big.Int))
+	if r.Sign() != 0 && new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		q.Add(q, big.NewInt(int64(x.Sign()*d.Sign())))
+	}
+	return m.with(q)
+}
+
+// Compare return -1, 0 or 1 when m is less than, equal or greater than o
+func (m Money) Compare(o Money) (int, error) {
+	if err := m.same(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Units < o.Units:
+		return -1, nil
+	case m.Units > o.Units:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Allocate split m according to ratios without losing any minor unit, the
+// remainder is distributed one unit at a time starting from the first share,
+// e.g. allocating $0.05 by (3, 7) gives $0.02 and $0.03
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("kitgo: %w: %d", ErrMoneyRatio, r)
+		}
+		total.Add(total, big.NewInt(r))
+	}
+	if total.Sign() <= 0 {
+		return nil, fmt.Errorf("kitgo: %w: ratios sum to zero", ErrMoneyRatio)
+	}
+	units := big.NewInt(m.Units)
+	rest := new(big.Int).Abs(units)
+	shares := make([]Money, len(ratios))
+	for i, r := range ratios {
+		q := new(big.Int).Mul(new(big.Int).Abs(units), big.NewInt(r))
+		q.Quo(q, total)
+		rest.Sub(rest, q)
+		shares[i] = Money{m.Tag, q.Int64() * int64(units.Sign()), m.Format}
+	}
+	// rest is less than the number of ratios, since each share is floored
+	for i := 0; rest.Sign() > 0; i++ {
+		if ratios[i] > 0 {
+			shares[i].Units += int64(units.Sign())
+			rest.Sub(rest, big.NewInt(1))
+		}
+	}
+	return shares, nil
+}
+
+// same ensure o is in the same currency unit as m, tags of different
+// language are allowed as long as the region resolves to the same currency,
+// tags which resolve to no currency are never the same
+func (m Money) same(o Money) error {
+	for _, tag := range []string{m.Tag, o.Tag} {
+		if _, ok := moneyCurrency(tag); !ok {
+			return fmt.Errorf("kitgo: %w: %q", ErrMoneyCurrency, tag)
+		}
+	}
+	a, b := moneyUnit(m.Tag), moneyUnit(o.Tag)
+	if a != b {
+		return fmt.Errorf("kitgo: %w: %s and %s", ErrCurrencyMismatch, a, b)
+	}
+	return nil
+}
+
+func (m Money) with(x *big.Int) (Money, error) {
+	if !x.IsInt64() {
+		return m, fmt.Errorf("kitgo: %w: %s units", ErrMoneyOverflow, x)
+	}
+	m.Units = x.Int64()
+	return m, nil
+}
+
+func moneyUnit(tag string) currency.Unit {
+	unit, _ := moneyCurrency(tag)
+	return unit
+}
+
+func moneyCurrency(tag string) (currency.Unit, bool) {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return currency.XXX, false
+	}
+	unit, conf := currency.FromTag(t)
+	return unit, conf != language.No
+}
+
+func moneyScale(tag string) int {
+	t, _ := language.Parse(tag)
+	region, _ := t.Region()
+	return currencyDecimals(region.String())
+}
@@ -0,0 +1,118 @@
+package kitgo_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_money(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	usd := func(units int64) kitgo.Money { return kitgo.Money{Tag: "en-US", Units: units} }
+
+	t.Run("Currency", func(t *testing.T) {
+		for _, tt := range []struct {
+			tag   string
+			value float64
+			units int64
+			scale int
+		}{
+			{"en-US", 10.505, 1051, 2},
+			{"en-US", -10.505, -1051, 2},
+			{"ar-KW", 1.2345, 1235, 3},
+			{"ja", 1234.5, 1235, 0},
+			{"id", 0.1 + 0.2, 30, 2},
+		} {
+			m, err := kitgo.Currency{Tag: tt.tag, Value: tt.value}.Money()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Units).To(Equal(tt.units), tt.tag)
+			Expect(m.Scale()).To(Equal(tt.scale), tt.tag)
+		}
+		// out of range values are not truncated silently
+		for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.MaxInt64 / 100, -math.MaxInt64 / 10} {
+			_, err := kitgo.Currency{Tag: "en-US", Value: value}.Money()
+			Expect(errors.Is(err, kitgo.ErrMoneyOverflow)).To(BeTrue(), "%v", value)
+		}
+		Expect(kitgo.Currency{Tag: "ja", Value: -math.Exp2(63)}.Money()).To(Equal(kitgo.Money{Tag: "ja", Units: math.MinInt64}))
+
+		m := kitgo.Money{Tag: "id", Units: 123456789}
+		Expect(m.Currency()).To(Equal(kitgo.Currency{Tag: "id", Value: 1234567.89}))
+		Expect(m.String()).To(Equal("Rp 1.234.567,89"))
+		b, err := m.MarshalJSON()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`"Rp 1.234.567,89"`))
+	})
+	t.Run("Add/Sub", func(t *testing.T) {
+		sum := usd(0)
+		for i := 0; i < 10; i++ {
+			var err error
+			cent, _ := kitgo.Currency{Tag: "en-US", Value: 0.1}.Money()
+			sum, err = sum.Add(cent)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(sum).To(Equal(usd(100)))
+		Expect(sum.Sub(usd(150))).To(Equal(usd(-50)))
+		// different language, same currency
+		Expect(sum.Add(kitgo.Money{Tag: "es-US", Units: 1})).To(Equal(usd(101)))
+
+		_, err := sum.Add(kitgo.Money{Tag: "ja", Units: 1})
+		Expect(errors.Is(err, kitgo.ErrCurrencyMismatch)).To(BeTrue())
+		Expect(err.Error()).To(Equal("kitgo: currency mismatch: USD and JPY"))
+		_, err = sum.Sub(kitgo.Money{Tag: "ja", Units: 1})
+		Expect(errors.Is(err, kitgo.ErrCurrencyMismatch)).To(BeTrue())
+		_, err = usd(math.MaxInt64).Add(usd(1))
+		Expect(errors.Is(err, kitgo.ErrMoneyOverflow)).To(BeTrue())
+		// malformed tags both resolve to XXX, they are not the same currency
+		_, err = kitgo.Money{Tag: "?", Units: 1}.Add(kitgo.Money{Tag: "!", Units: 1})
+		Expect(errors.Is(err, kitgo.ErrMoneyCurrency)).To(BeTrue())
+		Expect(err.Error()).To(Equal(`kitgo: invalid money currency: "?"`))
+		_, err = usd(1).Sub(kitgo.Money{Tag: "?", Units: 1})
+		Expect(errors.Is(err, kitgo.ErrMoneyCurrency)).To(BeTrue())
+	})
+	t.Run("Mul", func(t *testing.T) {
+		for _, tt := range [][4]int64{
+			{1000, 11, 100, 110},
+			{1005, 1, 10, 101},
+			{1004, 1, 10, 100},
+			{-1005, 1, 10, -101},
+			{1005, -1, 10, -101},
+			{1005, 1, -10, -101},
+			{1000, 1, 3, 333},
+			{2000, 1, 3, 667},
+		} {
+			Expect(usd(tt[0]).Mul(tt[1], tt[2])).To(Equal(usd(tt[3])), "%v", tt)
+		}
+		_, err := usd(1).Mul(1, 0)
+		Expect(errors.Is(err, kitgo.ErrMoneyRatio)).To(BeTrue())
+		_, err = usd(math.MaxInt64).Mul(2, 1)
+		Expect(errors.Is(err, kitgo.ErrMoneyOverflow)).To(BeTrue())
+		// intermediate overflow is fine as long as the result fits
+		Expect(usd(math.MaxInt64).Mul(3, 3)).To(Equal(usd(math.MaxInt64)))
+	})
+	t.Run("Compare", func(t *testing.T) {
+		Expect(usd(1).Compare(usd(2))).To(Equal(-1))
+		Expect(usd(2).Compare(usd(2))).To(Equal(0))
+		Expect(usd(3).Compare(usd(2))).To(Equal(1))
+		_, err := usd(1).Compare(kitgo.Money{Tag: "ja"})
+		Expect(errors.Is(err, kitgo.ErrCurrencyMismatch)).To(BeTrue())
+	})
+	t.Run("Allocate", func(t *testing.T) {
+		Expect(usd(5).Allocate(3, 7)).To(Equal([]kitgo.Money{usd(2), usd(3)}))
+		Expect(usd(100).Allocate(1, 1, 1)).To(Equal([]kitgo.Money{usd(34), usd(33), usd(33)}))
+		Expect(usd(-100).Allocate(1, 1, 1)).To(Equal([]kitgo.Money{usd(-34), usd(-33), usd(-33)}))
+		Expect(usd(2).Allocate(0, 1, 1, 1)).To(Equal([]kitgo.Money{usd(0), usd(1), usd(1), usd(0)}))
+		Expect(usd(math.MaxInt64).Allocate(math.MaxInt64, 1)).To(Equal([]kitgo.Money{usd(math.MaxInt64), usd(0)}))
+
+		_, err := usd(1).Allocate()
+		Expect(errors.Is(err, kitgo.ErrMoneyRatio)).To(BeTrue())
+		_, err = usd(1).Allocate(0)
+		Expect(errors.Is(err, kitgo.ErrMoneyRatio)).To(BeTrue())
+		_, err = usd(1).Allocate(1, -1)
+		Expect(errors.Is(err, kitgo.ErrMoneyRatio)).To(BeTrue())
+	})
+}
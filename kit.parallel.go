@@ -0,0 +1,112 @@
+package kitgo
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// ParallelConfig configure ParallelPool
+type ParallelConfig struct {
+	// MaxConcurrency limit the number of running tasks, zero or negative means
+	// every task is running at once
+	MaxConcurrency int
+
+	// FailFast cancel the context of every task on the first error, the tasks
+	// which have not been started are not run
+	FailFast bool
+}
+
+// ParallelResult is the outcome of a task, at the same index as the task
+type ParallelResult struct {
+	Value interface{}
+	Err   error
+}
+
+// PanicError is returned when a task panic, Value is the recovered value and
+// Stack is the stack trace of the panicking goroutine
+type PanicError struct {
+	Index int
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("kitgo: task %d panic: %v", e.Index, e.Value)
+}
+
+// Unwrap return the recovered value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ParallelPool run tasks with at most conf.MaxConcurrency running at once and
+// wait for all of them, a nil task is skipped, each task receive a context
+// that is canceled once ParallelPool return or, with conf.FailFast, on the
+// first error, a panic is recovered as *PanicError
+//
+// With conf.FailFast the first error is returned, otherwise every error is
+// returned as errorList, a task that is not started because the context is
+// done has ctx.Err() as its result
+func ParallelPool(ctx context.Context, conf *ParallelConfig, tasks ...func(context.Context) (interface{}, error)) ([]ParallelResult, error) {
+	if conf == nil {
+		conf = &ParallelConfig{}
+	}
+	n := conf.MaxConcurrency
+	if n <= 0 || n > len(tasks) {
+		n = len(tasks)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		first   error
+		sem     = make(chan struct{}, n)
+		results = make([]ParallelResult, len(tasks))
+	)
+	for i := range tasks {
+		if tasks[i] == nil {
+			continue
+		}
+		if ctx.Err() == nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			results[i].Value, results[i].Err = runTask(ctx, i, tasks[i])
+			if results[i].Err != nil && conf.FailFast {
+				once.Do(func() { first = results[i].Err; cancel() })
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if first != nil {
+		return results, first
+	}
+	var errs errorList
+	for i := range results {
+		errs = errs.Append(results[i].Err)
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
+
+func runTask(ctx context.Context, i int, task func(context.Context) (interface{}, error)) (v interface{}, err error) {
+	defer RecoverWith(func(recv interface{}) { err = &PanicError{i, recv, debug.Stack()} })
+	return task(ctx)
+}
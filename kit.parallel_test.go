@@ -0,0 +1,118 @@
+package kitgo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_parallel_pool(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	errTask := errors.New("task")
+
+	t.Run("results by index", func(t *testing.T) {
+		tasks := make([]func(context.Context) (interface{}, error), 10)
+		for i := range tasks {
+			i := i
+			tasks[i] = func(context.Context) (interface{}, error) {
+				time.Sleep(time.Duration(10-i) * time.Millisecond)
+				return i * i, nil
+			}
+		}
+		tasks[3] = nil
+		results, err := kitgo.ParallelPool(ctx, nil, tasks...)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(10))
+		for i := range results {
+			if i == 3 {
+				Expect(results[i]).To(Equal(kitgo.ParallelResult{}))
+				continue
+			}
+			Expect(results[i]).To(Equal(kitgo.ParallelResult{Value: i * i}))
+		}
+
+		results, err = kitgo.ParallelPool(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(BeEmpty())
+	})
+	t.Run("max concurrency", func(t *testing.T) {
+		running, peak := int64(0), int64(0)
+		task := func(context.Context) (interface{}, error) {
+			n := atomic.AddInt64(&running, 1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+			return nil, nil
+		}
+		tasks := []func(context.Context) (interface{}, error){task, task, task, task, task, task, task, task}
+		_, err := kitgo.ParallelPool(ctx, &kitgo.ParallelConfig{MaxConcurrency: 3}, tasks...)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt64(&peak)).To(BeNumerically("<=", 3))
+		Expect(atomic.LoadInt64(&peak)).To(BeNumerically(">", 1))
+	})
+	t.Run("collect every error", func(t *testing.T) {
+		results, err := kitgo.ParallelPool(ctx, &kitgo.ParallelConfig{MaxConcurrency: 1},
+			func(context.Context) (interface{}, error) { return nil, errTask },
+			func(context.Context) (interface{}, error) { return 1, nil },
+			func(context.Context) (interface{}, error) { return nil, fmt.Errorf("wrapped: %w", errTask) },
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("task\nwrapped: task"))
+		Expect(errors.Is(err, errTask)).To(BeTrue())
+		Expect(results[1]).To(Equal(kitgo.ParallelResult{Value: 1}))
+	})
+	t.Run("fail fast", func(t *testing.T) {
+		started := int64(0)
+		wait := func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt64(&started, 1)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		results, err := kitgo.ParallelPool(ctx, &kitgo.ParallelConfig{MaxConcurrency: 2, FailFast: true},
+			wait,
+			func(context.Context) (interface{}, error) { return nil, errTask },
+			wait, wait, wait,
+		)
+		Expect(err).To(Equal(errTask))
+		Expect(atomic.LoadInt64(&started)).To(Equal(int64(1)))
+		Expect(results[0].Err).To(Equal(context.Canceled))
+		Expect(results[4].Err).To(Equal(context.Canceled))
+	})
+	t.Run("parent context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		results, err := kitgo.ParallelPool(ctx, nil, func(context.Context) (interface{}, error) { return 1, nil })
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(results[0].Value).To(BeNil())
+	})
+	t.Run("panic", func(t *testing.T) {
+		results, err := kitgo.ParallelPool(ctx, nil,
+			func(context.Context) (interface{}, error) { panic("boom") },
+			func(context.Context) (interface{}, error) { panic(errTask) },
+		)
+		Expect(err).To(HaveOccurred())
+		pe := new(kitgo.PanicError)
+		Expect(errors.As(results[0].Err, &pe)).To(BeTrue())
+		Expect(pe.Index).To(Equal(0))
+		Expect(pe.Value).To(Equal("boom"))
+		Expect(string(pe.Stack)).To(ContainSubstring("panic"))
+		Expect(pe.Error()).To(Equal("kitgo: task 0 panic: boom"))
+		Expect(errors.Is(results[0].Err, errTask)).To(BeFalse())
+		Expect(errors.Is(results[1].Err, errTask)).To(BeTrue())
+		Expect(errors.Is(err, errTask)).To(BeTrue())
+	})
+}
@@ -0,0 +1,435 @@
+package kitgo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// RateLimitTokenBucket refill Burst tokens at Limit per Period, each
+	// request take a token
+	RateLimitTokenBucket = "token-bucket"
+	// RateLimitSlidingWindow keep the time of each request within the last
+	// Period, at most Limit of them
+	RateLimitSlidingWindow = "sliding-window"
+	// RateLimitGCRA is the generic cell rate algorithm, a token bucket which
+	// only store the theoretical arrival time
+	RateLimitGCRA = "gcra"
+)
+
+var RateLimit rateLimit_
+
+type rateLimit_ struct{}
+
+// RateLimitConfig configure a rate limiter, Limit and Period are required
+type RateLimitConfig struct {
+	// Algorithm is one of RateLimitTokenBucket, RateLimitSlidingWindow or
+	// RateLimitGCRA, default to RateLimitTokenBucket
+	Algorithm string
+
+	// Limit is the number of requests allowed per Period
+	Limit  int
+	Period time.Duration
+
+	// Burst is the number of requests allowed at once by RateLimitTokenBucket
+	// and RateLimitGCRA, default to Limit
+	Burst int
+
+	// Prefix is prepended to each key stored in Redis, default to
+	// "kitgo:ratelimit:"
+	Prefix string
+
+	// Now default to time.Now, only used by RateLimit.Memory, the Redis
+	// backend use the clock of the server so every replica agree
+	Now func() time.Time
+}
+
+// RateLimiterI is implemented by RateLimit.Memory and RateLimit.Redis
+type RateLimiterI interface {
+	// Allow report whether n requests of key are allowed now, those are
+	// counted only when allowed, an n below 1 is an error
+	Allow(ctx context.Context, key string, n int) (RateLimitResult, error)
+}
+
+// RateLimitResult is the outcome of RateLimiterI.Allow, RetryAfter is zero
+// when allowed and negative when n is above Limit, so that retrying is useless,
+// ResetAfter is the wait until the limit is fully available
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// Memory return a limiter local to the process
+func (rateLimit_) Memory(conf *RateLimitConfig) *RateLimitMemory {
+	x := &RateLimitMemory{conf: rateLimitConfig(conf), states: map[string]*rateLimitState{}}
+	if x.conf.Now == nil {
+		x.conf.Now = time.Now
+	}
+	return x
+}
+
+// Redis return a limiter shared by every process using the same Redis, each
+// call is a single atomic Lua script
+func (rateLimit_) Redis(client *RedisWrapper, conf *RateLimitConfig) *RateLimitRedis {
+	PanicWhen(client == nil, "kitgo: rate limit redis client is required")
+	x := &RateLimitRedis{conf: rateLimitConfig(conf), client: client}
+	if x.conf.Prefix == "" {
+		x.conf.Prefix = "kitgo:ratelimit:"
+	}
+	return x
+}
+
+func rateLimitConfig(conf *RateLimitConfig) RateLimitConfig {
+	PanicWhen(conf == nil || conf.Limit < 1 || conf.Period <= 0, "kitgo: rate limit should have a positive limit and period")
+	c := *conf
+	if c.Algorithm == "" {
+		c.Algorithm = RateLimitTokenBucket
+	}
+	_, ok := rateLimitScripts[c.Algorithm]
+	PanicWhen(!ok, fmt.Sprintf("kitgo: unknown rate limit algorithm %q", c.Algorithm))
+	if c.Burst < 1 {
+		c.Burst = c.Limit
+	}
+	return c
+}
+
+type RateLimitMemory struct {
+	conf   RateLimitConfig
+	mu     sync.Mutex
+	states map[string]*rateLimitState
+	swept  time.Time
+}
+
+// rateLimitState is the state of a key, tokens and at are the remaining
+// tokens and the last refill of RateLimitTokenBucket, at is the theoretical
+// arrival time of RateLimitGCRA and log is used by RateLimitSlidingWindow
+type rateLimitState struct {
+	tokens float64
+	at     time.Time
+	log    []time.Time
+	expire time.Time
+}
+
+// Allow implement RateLimiterI
+func (x *RateLimitMemory) Allow(ctx context.Context, key string, n int) (RateLimitResult, error) {
+	var _ RateLimiterI = x
+	if n < 1 {
+		return RateLimitResult{}, fmt.Errorf("kitgo: rate limit: n %d should be positive", n)
+	}
+	if err := ctx.Err(); err != nil {
+		return RateLimitResult{}, err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := x.conf.Now()
+	x.sweep(now)
+	s, ok := x.states[key]
+	if !ok {
+		s = &rateLimitState{tokens: float64(x.conf.Burst), at: now}
+		x.states[key] = s
+	}
+	var res RateLimitResult
+	switch x.conf.Algorithm {
+	case RateLimitSlidingWindow:
+		res = x.slidingWindow(s, now, n)
+	case RateLimitGCRA:
+		res = x.gcra(s, now, n)
+	default:
+		res = x.tokenBucket(s, now, n)
+	}
+	s.expire = now.Add(res.ResetAfter)
+	if n > res.Limit {
+		res.RetryAfter = -1
+	}
+	return res, nil
+}
+
+// sweep forget the keys which are fully available, at most once per Period
+func (x *RateLimitMemory) sweep(now time.Time) {
+	if now.Sub(x.swept) < x.conf.Period {
+		return
+	}
+	x.swept = now
+	for k, s := range x.states {
+		if !now.Before(s.expire) {
+			delete(x.states, k)
+		}
+	}
+}
+
+func (x *RateLimitMemory) tokenBucket(s *rateLimitState, now time.Time, n int) RateLimitResult {
+	c := x.conf
+	rate := float64(c.Limit) / float64(c.Period)
+	if elapsed := now.Sub(s.at); elapsed > 0 {
+		s.tokens = math.Min(float64(c.Burst), s.tokens+float64(elapsed)*rate)
+		s.at = now
+	}
+	res := RateLimitResult{Limit: c.Burst}
+	if s.tokens >= float64(n) {
+		s.tokens -= float64(n)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((float64(n) - s.tokens) / rate))
+	}
+	res.Remaining = int(s.tokens)
+	res.ResetAfter = time.Duration(math.Ceil((float64(c.Burst) - s.tokens) / rate))
+	return res
+}
+
+func (x *RateLimitMemory) slidingWindow(s *rateLimitState, now time.Time, n int) RateLimitResult {
+	c := x.conf
+	i := sort.Search(len(s.log), func(i int) bool { return s.log[i].After(now.Add(-c.Period)) })
+	s.log = s.log[i:]
+	res := RateLimitResult{Limit: c.Limit}
+	if count := len(s.log); count+n <= c.Limit {
+		for j := 0; j < n; j++ {
+			s.log = append(s.log, now)
+		}
+		res.Allowed = true
+	} else if k := count + n - c.Limit - 1; k < count {
+		res.RetryAfter = s.log[k].Add(c.Period).Sub(now)
+	} else {
+		res.RetryAfter = c.Period
+	}
+	res.Remaining = c.Limit - len(s.log)
+	if len(s.log) > 0 {
+		res.ResetAfter = s.log[len(s.log)-1].Add(c.Period).Sub(now)
+	}
+	return res
+}
+
+func (x *RateLimitMemory) gcra(s *rateLimitState, now time.Time, n int) RateLimitResult {
+	c := x.conf
+	interval := c.Period / time.Duration(c.Limit)
+	tolerance := interval * time.Duration(c.Burst)
+	tat := s.at
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval * time.Duration(n))
+	res := RateLimitResult{Limit: c.Burst}
+	if allowAt := next.Add(-tolerance); allowAt.After(now) {
+		res.RetryAfter = allowAt.Sub(now)
+		next = tat
+	} else {
+		s.at = next
+		res.Allowed = true
+	}
+	res.Remaining = int(now.Add(tolerance).Sub(next) / interval)
+	res.ResetAfter = next.Sub(now)
+	return res
+}
+
+type RateLimitRedis struct {
+	conf   RateLimitConfig
+	client *RedisWrapper
+}
+
+// Allow implement RateLimiterI
+func (x *RateLimitRedis) Allow(ctx context.Context, key string, n int) (RateLimitResult, error) {
+	var _ RateLimiterI = x
+	if n < 1 {
+		return RateLimitResult{}, fmt.Errorf("kitgo: rate limit: n %d should be positive", n)
+	}
+	c := x.conf
+	v, err := rateLimitScripts[c.Algorithm].Run(ctx, x.client, []string{c.Prefix + key},
+		c.Limit, c.Period.Microseconds(), c.Burst, n).Result()
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("kitgo: rate limit: %w", err)
+	}
+	reply, _ := v.([]interface{})
+	nums := make([]int64, len(reply))
+	for i := range reply {
+		nums[i], _ = reply[i].(int64)
+	}
+	if len(nums) != 4 {
+		return RateLimitResult{}, fmt.Errorf("kitgo: rate limit: unexpected reply %v", v)
+	}
+	limit := c.Burst
+	if c.Algorithm == RateLimitSlidingWindow {
+		limit = c.Limit
+	}
+	res := RateLimitResult{
+		Allowed:    nums[0] == 1,
+		Limit:      limit,
+		Remaining:  int(nums[1]),
+		RetryAfter: time.Duration(nums[2]) * time.Microsecond,
+		ResetAfter: time.Duration(nums[3]) * time.Microsecond,
+	}
+	if n > limit {
+		res.RetryAfter = -1
+	}
+	return res, nil
+}
+
+// rateLimitScripts mirror the algorithms of RateLimitMemory in microseconds,
+// ARGV are limit, period, burst and n, the reply is allowed, remaining, retry
+// after and reset after, the clock of the server is used so commands are
+// replicated by effect, numbers are passed to redis.call as is since Redis
+// format them with full precision unlike tostring
+var rateLimitScripts = map[string]*redis.Script{
+	RateLimitTokenBucket: redis.NewScript(rateLimitLua + `
+local rate = limit / period
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens, at = tonumber(state[1]) or burst, tonumber(state[2]) or now
+if now > at then
+	tokens, at = math.min(burst, tokens + (now - at) * rate), now
+end
+local allowed, retry = 0, 0
+if tokens >= n then
+	tokens, allowed = tokens - n, 1
+else
+	retry = math.ceil((n - tokens) / rate)
+end
+local reset = math.ceil((burst - tokens) / rate)
+redis.call('HMSET', KEYS[1], 'tokens', tokens, 'at', at)
+redis.call('PEXPIRE', KEYS[1], math.ceil(reset / 1000) + 1)
+return {allowed, math.floor(tokens), retry, reset}
+`),
+	RateLimitSlidingWindow: redis.NewScript(rateLimitLua + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed, retry = 0, 0
+if count + n <= limit then
+	for i = 1, n do
+		redis.call('ZADD', KEYS[1], now, string.format('%d:%d', now, count + i))
+	end
+	count, allowed = count + n, 1
+else
+	local at = redis.call('ZRANGE', KEYS[1], count + n - limit - 1, count + n - limit - 1, 'WITHSCORES')
+	retry = period
+	if at[2] then
+		retry = tonumber(at[2]) + period - now
+	end
+end
+local reset = 0
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if last[2] then
+	reset = tonumber(last[2]) + period - now
+	redis.call('PEXPIRE', KEYS[1], math.ceil(reset / 1000) + 1)
+end
+return {allowed, limit - count, retry, reset}
+`),
+	RateLimitGCRA: redis.NewScript(rateLimitLua + `
+local interval = period / limit
+local tolerance = interval * burst
+local tat = math.max(tonumber(redis.call('GET', KEYS[1])) or now, now)
+local new = tat + interval * n
+local allowed, retry = 0, 0
+if new - tolerance > now then
+	retry, new = math.ceil(new - tolerance - now), tat
+else
+	allowed = 1
+	redis.call('SET', KEYS[1], new, 'PX', math.ceil((new - now) / 1000) + 1)
+end
+return {allowed, math.floor((now + tolerance - new) / interval), retry, math.ceil(new - now)}
+`),
+}
+
+const rateLimitLua = `
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local limit, period, burst, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+`
+
+// RateLimitHandlerConfig configure RateLimit.Handler, Limiter is required
+type RateLimitHandlerConfig struct {
+	Limiter RateLimiterI
+
+	// Key identify the client of a request, an empty key is not limited,
+	// default to RateLimit.ByIP()
+	Key func(*http.Request) string
+
+	// OnLimited respond to a request over the limit, default to 429 Too Many
+	// Requests, the headers are already set
+	OnLimited http.Handler
+
+	// OnError is called when Limiter fail, the request is then served
+	OnError func(*http.Request, error)
+}
+
+// Handler limit the requests served by next, every response has the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and
+// Retry-After when limited, durations are rounded up to seconds
+func (rateLimit_) Handler(conf *RateLimitHandlerConfig, next http.Handler) http.Handler {
+	PanicWhen(conf == nil || conf.Limiter == nil, "kitgo: rate limit handler should have a limiter")
+	c := *conf
+	if c.Key == nil {
+		c.Key = RateLimit.ByIP()
+	}
+	if c.OnLimited == nil {
+		c.OnLimited = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			code := http.StatusTooManyRequests
+			http.Error(w, http.StatusText(code), code)
+		})
+	}
+	seconds := func(d time.Duration) string { return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10) }
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := c.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		res, err := c.Limiter.Allow(r.Context(), key, 1)
+		if err != nil {
+			if c.OnError != nil {
+				c.OnError(r, err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set(RateLimitLimit, strconv.Itoa(res.Limit))
+		h.Set(RateLimitRemaining, strconv.Itoa(res.Remaining))
+		h.Set(RateLimitReset, seconds(res.ResetAfter))
+		if !res.Allowed {
+			h.Set(RetryAfter, seconds(res.RetryAfter))
+			c.OnLimited.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ByIP key a request by the host of RemoteAddr, or by the first address of
+// the first non-empty header, e.g. "X-Forwarded-For" set by a trusted proxy
+func (rateLimit_) ByIP(headers ...string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		for _, name := range headers {
+			if v := strings.TrimSpace(strings.Split(r.Header.Get(name), ",")[0]); v != "" {
+				return v
+			}
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	}
+}
+
+// ByHeader key a request by the value of a header, e.g. "X-Api-Key"
+func (rateLimit_) ByHeader(name string) func(*http.Request) string {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// ByNamedArg key a request by a named argument of MuxMatcher.Pattern, see
+// GetNamedArgsFromRequest
+func (rateLimit_) ByNamedArg(name string) func(*http.Request) string {
+	return func(r *http.Request) string { return HTTP.Handler.GetNamedArgsFromRequest(r).Get(name) }
+}
@@ -0,0 +1,193 @@
+package kitgo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_ratelimit(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	t0 := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	memory := func(algorithm string) (*kitgo.RateLimitMemory, *time.Time) {
+		now := t0
+		return kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{
+			Algorithm: algorithm, Limit: 2, Period: time.Second, Now: func() time.Time { return now },
+		}), &now
+	}
+	allow := func(limiter kitgo.RateLimiterI, key string, n int) kitgo.RateLimitResult {
+		res, err := limiter.Allow(ctx, key, n)
+		Expect(err).NotTo(HaveOccurred())
+		return res
+	}
+
+	t.Run("token bucket", func(t *testing.T) {
+		limiter, now := memory("")
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 500 * time.Millisecond}))
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 0, RetryAfter: 500 * time.Millisecond, ResetAfter: time.Second}))
+		Expect(allow(limiter, "b", 2).Allowed).To(BeTrue())
+		*now = now.Add(500 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		*now = now.Add(time.Hour)
+		// more than the capacity is never allowed
+		Expect(allow(limiter, "a", 3)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 2, RetryAfter: -1}))
+	})
+	t.Run("sliding window", func(t *testing.T) {
+		limiter, now := memory(kitgo.RateLimitSlidingWindow)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Second}))
+		*now = now.Add(100 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		*now = now.Add(100 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 0, RetryAfter: 800 * time.Millisecond, ResetAfter: 900 * time.Millisecond}))
+		Expect(allow(limiter, "a", 3)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 0, RetryAfter: -1, ResetAfter: 900 * time.Millisecond}))
+		*now = now.Add(800 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		Expect(allow(limiter, "b", 3)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 2, RetryAfter: -1}))
+	})
+	t.Run("GCRA", func(t *testing.T) {
+		limiter, now := memory(kitgo.RateLimitGCRA)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 500 * time.Millisecond}))
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Limit: 2, Remaining: 0, RetryAfter: 500 * time.Millisecond, ResetAfter: time.Second}))
+		*now = now.Add(500 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		*now = now.Add(2 * time.Second)
+		Expect(allow(limiter, "a", 2)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}))
+		Expect(allow(limiter, "a", 3).RetryAfter).To(Equal(time.Duration(-1)))
+
+		limiter, now = memory(kitgo.RateLimitGCRA)
+		Expect(allow(limiter, "a", 1).Allowed).To(BeTrue())
+		*now = now.Add(600 * time.Millisecond)
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 500 * time.Millisecond}))
+	})
+	t.Run("Memory", func(t *testing.T) {
+		limiter := kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{Limit: 1, Period: time.Hour, Burst: 3})
+		Expect(allow(limiter, "a", 3).Allowed).To(BeTrue())
+		Expect(allow(limiter, "a", 1).Allowed).To(BeFalse())
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := limiter.Allow(canceled, "a", 1)
+		Expect(err).To(Equal(context.Canceled))
+		for _, n := range []int{0, -1} {
+			_, err = limiter.Allow(ctx, "a", n)
+			Expect(err).To(MatchError(fmt.Sprintf("kitgo: rate limit: n %d should be positive", n)))
+		}
+
+		Expect(func() { kitgo.RateLimit.Memory(nil) }).To(Panic())
+		Expect(func() { kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{Period: time.Second}) }).To(Panic())
+		Expect(func() { kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{Limit: 1}) }).To(Panic())
+		Expect(func() {
+			kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{Algorithm: "leaky", Limit: 1, Period: time.Second})
+		}).To(Panic())
+	})
+	t.Run("Redis", func(t *testing.T) {
+		client, mock := kitgo.Redis.Test()
+		defer func() { Expect(mock.ExpectationsWereMet()).To(Succeed()) }()
+		limiter := kitgo.RateLimit.Redis(client, &kitgo.RateLimitConfig{Algorithm: kitgo.RateLimitSlidingWindow, Limit: 2, Period: time.Second})
+
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"kitgo:ratelimit:a"}, 2, int64(1e6), 2, 1).
+			SetVal([]interface{}{int64(1), int64(1), int64(0), int64(1e6)})
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Second}))
+
+		// the script is loaded by EVAL when missing from the script cache
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"kitgo:ratelimit:a"}, 2, int64(1e6), 2, 1).
+			SetErr(errors.New("NOSCRIPT No matching script"))
+		mock.Regexp().ExpectEval("ZREMRANGEBYSCORE", []string{"kitgo:ratelimit:a"}, 2, int64(1e6), 2, 1).
+			SetVal([]interface{}{int64(0), int64(0), int64(800000), int64(900000)})
+		Expect(allow(limiter, "a", 1)).To(Equal(kitgo.RateLimitResult{Limit: 2, RetryAfter: 800 * time.Millisecond, ResetAfter: 900 * time.Millisecond}))
+
+		limiter = kitgo.RateLimit.Redis(client, &kitgo.RateLimitConfig{Algorithm: kitgo.RateLimitGCRA, Limit: 10, Period: time.Minute, Burst: 5, Prefix: "rl:"})
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"rl:b"}, 10, int64(60e6), 5, 1).
+			SetVal([]interface{}{int64(1), int64(4), int64(0), int64(6e6)})
+		Expect(allow(limiter, "b", 1)).To(Equal(kitgo.RateLimitResult{Allowed: true, Limit: 5, Remaining: 4, ResetAfter: 6 * time.Second}))
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"rl:b"}, 10, int64(60e6), 5, 6).
+			SetVal([]interface{}{int64(0), int64(4), int64(6e6), int64(6e6)})
+		Expect(allow(limiter, "b", 6)).To(Equal(kitgo.RateLimitResult{Limit: 5, Remaining: 4, RetryAfter: -1, ResetAfter: 6 * time.Second}))
+
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"rl:b"}, 10, int64(60e6), 5, 1).SetErr(errors.New("down"))
+		_, err := limiter.Allow(ctx, "b", 1)
+		Expect(err).To(MatchError("kitgo: rate limit: down"))
+		mock.Regexp().ExpectEvalSha("^[0-9a-f]{40}$", []string{"rl:b"}, 10, int64(60e6), 5, 1).SetVal("OK")
+		_, err = limiter.Allow(ctx, "b", 1)
+		Expect(err).To(MatchError("kitgo: rate limit: unexpected reply OK"))
+		// n is checked before any call to redis
+		_, err = limiter.Allow(ctx, "b", 0)
+		Expect(err).To(MatchError("kitgo: rate limit: n 0 should be positive"))
+
+		Expect(func() { kitgo.RateLimit.Redis(nil, &kitgo.RateLimitConfig{Limit: 1, Period: time.Second}) }).To(Panic())
+	})
+	t.Run("Handler", func(t *testing.T) {
+		ok := kitgo.HTTP.Handler.ResponseWith(kitgo.HTTP.Handler.NewResponseState(200, nil, []byte("ok"), ""))
+		limiter := kitgo.RateLimit.Memory(&kitgo.RateLimitConfig{Limit: 1, Period: time.Minute})
+		handler := kitgo.RateLimit.Handler(&kitgo.RateLimitHandlerConfig{Limiter: limiter}, ok)
+
+		w, r := kitgo.HTTP.Handler.Test("", "/", nil)
+		handler.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get(kitgo.RateLimitLimit)).To(Equal("1"))
+		Expect(w.Header().Get(kitgo.RateLimitRemaining)).To(Equal("0"))
+		Expect(w.Header().Get(kitgo.RateLimitReset)).To(Equal("60"))
+		Expect(w.Header().Get(kitgo.RetryAfter)).To(BeEmpty())
+
+		w, r = kitgo.HTTP.Handler.Test("", "/", nil)
+		handler.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(w.Body.String()).To(Equal("Too Many Requests\n"))
+		Expect(w.Header().Get(kitgo.RetryAfter)).To(Equal("60"))
+
+		// another address behind a proxy
+		w, r = kitgo.HTTP.Handler.Test("", "/", nil)
+		r.Header.Set("X-Forwarded-For", "203.0.113.1, 10.0.0.1")
+		kitgo.RateLimit.Handler(&kitgo.RateLimitHandlerConfig{Limiter: limiter, Key: kitgo.RateLimit.ByIP("X-Real-Ip", "X-Forwarded-For")}, ok).ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(kitgo.RateLimit.ByIP()(&http.Request{RemoteAddr: "@"})).To(Equal("@"))
+
+		// without key the request is not limited
+		byHeader := kitgo.RateLimit.Handler(&kitgo.RateLimitHandlerConfig{Limiter: limiter, Key: kitgo.RateLimit.ByHeader("X-Api-Key")}, ok)
+		for i := 0; i < 2; i++ {
+			w, r = kitgo.HTTP.Handler.Test("", "/", nil)
+			byHeader.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get(kitgo.RateLimitLimit)).To(BeEmpty())
+		}
+		w, r = kitgo.HTTP.Handler.Test("", "/", nil)
+		r.Header.Set("X-Api-Key", "k")
+		byHeader.ServeHTTP(w, r)
+		Expect(w.Header().Get(kitgo.RateLimitRemaining)).To(Equal("0"))
+
+		mux := kitgo.HTTP.Handler.Mux().Handle(http.MethodGet, "/users/:id", kitgo.RateLimit.Handler(&kitgo.RateLimitHandlerConfig{
+			Limiter:   limiter,
+			Key:       kitgo.RateLimit.ByNamedArg("id"),
+			OnLimited: kitgo.HTTP.Handler.ResponseWith(kitgo.HTTP.Handler.NewResponseState(503, nil, nil, "")),
+		}, ok))
+		for _, code := range []int{200, 503} {
+			w, r = kitgo.HTTP.Handler.Test("", "/users/42", nil)
+			mux.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(code))
+		}
+
+		// the request is served when the limiter fail
+		var failure error
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		for _, onError := range []func(*http.Request, error){nil, func(_ *http.Request, err error) { failure = err }} {
+			w, r = kitgo.HTTP.Handler.Test("", "/", nil)
+			kitgo.RateLimit.Handler(&kitgo.RateLimitHandlerConfig{Limiter: limiter, OnError: onError}, ok).ServeHTTP(w, r.WithContext(canceled))
+			Expect(w.Code).To(Equal(http.StatusOK))
+		}
+		Expect(failure).To(Equal(context.Canceled))
+
+		Expect(func() { kitgo.RateLimit.Handler(nil, ok) }).To(Panic())
+	})
+}
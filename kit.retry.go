@@ -0,0 +1,136 @@
+package kitgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var Retry retry_
+
+type retry_ struct{}
+
+// RetryBackoff return the delay before the next attempt, given the number of
+// attempt so far (starting from 1) and the previous delay (0 on the first call)
+type RetryBackoff func(attempt int, prev time.Duration) time.Duration
+
+// RetryConfig configure Retry.Do, the zero value retry until the task succeed
+// with exponential backoff starting from 100ms up to 10s
+type RetryConfig struct {
+	// MaxAttempts limit the number of attempt including the first one, zero or
+	// negative means no limit
+	MaxAttempts int
+
+	// MaxElapsedTime stop retrying when the next attempt would start after it,
+	// measured from the first attempt, zero means no limit
+	MaxElapsedTime time.Duration
+
+	// Backoff default to Retry.Exponential(100*time.Millisecond, 10*time.Second)
+	Backoff RetryBackoff
+
+	// Retryable report whether an error should be retried, default to any
+	// error except context.Canceled and context.DeadlineExceeded
+	Retryable func(error) bool
+
+	// OnAttempt is called after each attempt, e.g. to log or count it
+	OnAttempt func(RetryAttempt)
+}
+
+// RetryAttempt report a single attempt, Delay is the wait before the next
+// attempt or zero when there is no next attempt
+type RetryAttempt struct {
+	Attempt int
+	Err     error
+	Delay   time.Duration
+	Elapsed time.Duration
+}
+
+// Do run task until it succeed, the error is not retryable or a limit of conf
+// is reached, in which case the last error is returned wrapped, if ctx is done
+// while waiting both the last error and ctx.Err() are returned as errorList
+func (retry_) Do(ctx context.Context, conf *RetryConfig, task func() error) error {
+	if conf == nil {
+		conf = &RetryConfig{}
+	}
+	backoff, retryable := conf.Backoff, conf.Retryable
+	if backoff == nil {
+		backoff = Retry.Exponential(100*time.Millisecond, 10*time.Second)
+	}
+	if retryable == nil {
+		retryable = func(err error) bool {
+			return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	start, delay := time.Now(), time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		err := task()
+		a := RetryAttempt{Attempt: attempt, Err: err}
+		done, giveUp := err == nil || !retryable(err), false
+		if !done {
+			delay = backoff(attempt, delay)
+			giveUp = (conf.MaxAttempts > 0 && attempt >= conf.MaxAttempts) ||
+				(conf.MaxElapsedTime > 0 && time.Since(start)+delay > conf.MaxElapsedTime)
+			if !giveUp {
+				a.Delay = delay
+			}
+		}
+		a.Elapsed = time.Since(start)
+		if conf.OnAttempt != nil {
+			conf.OnAttempt(a)
+		}
+		if done {
+			return err
+		}
+		if giveUp {
+			return fmt.Errorf("kitgo: retry gave up after %d attempts: %w", attempt, err)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return NewErrors(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// Constant backoff always wait for d
+func (retry_) Constant(d time.Duration) RetryBackoff {
+	return func(int, time.Duration) time.Duration { return d }
+}
+
+// Exponential backoff wait for base * 2^(attempt-1), capped by max
+func (retry_) Exponential(base, max time.Duration) RetryBackoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		d := float64(base) * math.Pow(2, float64(attempt-1))
+		if d > float64(max) {
+			return max
+		}
+		return time.Duration(d)
+	}
+}
+
+// DecorrelatedJitter backoff wait for a random duration between base and 3
+// times the previous delay, capped by max, see
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func (retry_) DecorrelatedJitter(base, max time.Duration) RetryBackoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		d := base
+		if n := int64(prev*3 - base); n > 0 {
+			d += time.Duration(rand.Int63n(n))
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
@@ -0,0 +1,109 @@
+package kitgo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_retry(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	ctx := context.Background()
+	errTemp, errPerm := errors.New("temporary"), errors.New("permanent")
+	failing := func(n int, err error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			if calls++; calls <= n {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("succeed", func(t *testing.T) {
+		var attempts []kitgo.RetryAttempt
+		task, calls := failing(2, errTemp)
+		Expect(kitgo.Retry.Do(ctx, &kitgo.RetryConfig{
+			Backoff:   kitgo.Retry.Constant(time.Millisecond),
+			OnAttempt: func(a kitgo.RetryAttempt) { attempts = append(attempts, a) },
+		}, task)).To(Succeed())
+		Expect(*calls).To(Equal(3))
+		Expect(attempts).To(HaveLen(3))
+		Expect(attempts[0].Attempt).To(Equal(1))
+		Expect(attempts[0].Err).To(Equal(errTemp))
+		Expect(attempts[0].Delay).To(Equal(time.Millisecond))
+		Expect(attempts[2].Err).To(BeNil())
+		Expect(attempts[2].Delay).To(BeZero())
+		Expect(attempts[2].Elapsed).To(BeNumerically(">=", 2*time.Millisecond))
+
+		task, calls = failing(0, nil)
+		Expect(kitgo.Retry.Do(ctx, nil, task)).To(Succeed())
+		Expect(*calls).To(Equal(1))
+	})
+	t.Run("max attempts", func(t *testing.T) {
+		task, calls := failing(10, errTemp)
+		err := kitgo.Retry.Do(ctx, &kitgo.RetryConfig{MaxAttempts: 3, Backoff: kitgo.Retry.Constant(0)}, task)
+		Expect(errors.Is(err, errTemp)).To(BeTrue())
+		Expect(err.Error()).To(Equal("kitgo: retry gave up after 3 attempts: temporary"))
+		Expect(*calls).To(Equal(3))
+	})
+	t.Run("max elapsed time", func(t *testing.T) {
+		task, calls := failing(10, errTemp)
+		err := kitgo.Retry.Do(ctx, &kitgo.RetryConfig{
+			MaxElapsedTime: 10 * time.Millisecond,
+			Backoff:        kitgo.Retry.Constant(4 * time.Millisecond),
+		}, task)
+		Expect(errors.Is(err, errTemp)).To(BeTrue())
+		Expect(*calls).To(BeNumerically("<=", 3))
+	})
+	t.Run("retryable", func(t *testing.T) {
+		task, calls := failing(10, errPerm)
+		err := kitgo.Retry.Do(ctx, &kitgo.RetryConfig{
+			Retryable: func(err error) bool { return !errors.Is(err, errPerm) },
+		}, task)
+		Expect(err).To(Equal(errPerm))
+		Expect(*calls).To(Equal(1))
+
+		task, calls = failing(10, context.DeadlineExceeded)
+		Expect(kitgo.Retry.Do(ctx, nil, task)).To(Equal(context.DeadlineExceeded))
+		Expect(*calls).To(Equal(1))
+	})
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		task, _ := failing(10, errTemp)
+		err := kitgo.Retry.Do(ctx, &kitgo.RetryConfig{Backoff: kitgo.Retry.Constant(time.Second)}, task)
+		Expect(errors.Is(err, errTemp)).To(BeTrue())
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		task, calls := failing(0, nil)
+		Expect(kitgo.Retry.Do(ctx, nil, task)).To(Equal(context.DeadlineExceeded))
+		Expect(*calls).To(Equal(0))
+	})
+	t.Run("backoff", func(t *testing.T) {
+		exp := kitgo.Retry.Exponential(100*time.Millisecond, time.Second)
+		for attempt, expect := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+			Expect(exp(attempt+1, 0)).To(Equal(expect*time.Millisecond), "%d", attempt+1)
+		}
+		Expect(exp(5000, 0)).To(Equal(time.Second))
+
+		jitter := kitgo.Retry.DecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+		prev := time.Duration(0)
+		for attempt := 1; attempt < 50; attempt++ {
+			d := jitter(attempt, prev)
+			Expect(d).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(d).To(BeNumerically("<=", 100*time.Millisecond))
+			if prev >= 10*time.Millisecond {
+				Expect(d).To(BeNumerically("<", 3*prev))
+			}
+			prev = d
+		}
+		Expect(kitgo.Retry.DecorrelatedJitter(0, time.Second)(1, 0)).To(BeZero())
+	})
+}
@@ -0,0 +1,496 @@
+package kitgo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrSchemaInvalid is returned by Schema.Compile when the schema itself is
+// malformed or use an unsupported $ref
+var ErrSchemaInvalid = errors.New("invalid schema")
+
+var Schema schema_
+
+type schema_ struct{}
+
+// Compile parse a JSON Schema, either as JSON []byte or json.RawMessage, or
+// as a decoded json-like value such as Dict or bool
+//
+// The supported subset of draft 2020-12 is type, enum, const, properties,
+// additionalProperties, required, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, minLength, maxLength, minItems,
+// maxItems, minProperties, maxProperties, items, allOf, anyOf, oneOf and
+// $ref to a JSON Pointer within the same document such as "#/$defs/name",
+// any other keyword is ignored, a $ref may be recursive as long as it does not
+// loop back without descending into a property or an item
+func (schema_) Compile(schema interface{}) (*SchemaWrapper, error) {
+	root, err := decodePatch(schema)
+	if err != nil {
+		return nil, fmt.Errorf("kitgo: %w: %s", ErrSchemaInvalid, err)
+	}
+	c := &schemaCompiler{root: root, refs: map[string]*schemaNode{}}
+	node, err := c.compile(root, "#")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cycles(node); err != nil {
+		return nil, err
+	}
+	return &SchemaWrapper{node}, nil
+}
+
+// MustCompile is like Compile but panic on error, e.g. for package variable
+func (schema_) MustCompile(schema interface{}) *SchemaWrapper {
+	x, err := Schema.Compile(schema)
+	PanicWhen(err != nil, err)
+	return x
+}
+
+type SchemaWrapper struct{ node *schemaNode }
+
+// Validate check a json-like value such as Dict, List or the result of
+// JSON.Unmarshal, the returned error is either nil or an errorList of *Error
+// where Code is the failing keyword, Path is the JSON Pointer of the value and
+// Meta["schema"] is the location of the keyword in the schema
+func (x *SchemaWrapper) Validate(v interface{}) error {
+	if errs := x.node.validate(v, ""); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateJSON decode b with JSON then Validate it, a malformed JSON is
+// reported as an *Error with Code "json"
+func (x *SchemaWrapper) ValidateJSON(b []byte) error {
+	var v interface{}
+	if err := JSON.Unmarshal(b, &v); err != nil {
+		return NewErrors(&Error{Code: "json", Message: "invalid JSON", Err: err})
+	}
+	return x.Validate(v)
+}
+
+type schemaNode struct {
+	loc     string
+	always  *bool
+	types   []string
+	enum    []interface{}
+	hasEnum bool
+	cons    interface{}
+	hasCons bool
+	pattern *regexp.Regexp
+
+	minimum, maximum, exclusiveMinimum, exclusiveMaximum *float64
+
+	minLength, maxLength, minItems, maxItems, minProperties, maxProperties int
+
+	properties           map[string]*schemaNode
+	additionalProperties *schemaNode
+	required             []string
+	items                *schemaNode
+	ref                  *schemaNode
+	allOf, anyOf, oneOf  []*schemaNode
+}
+
+type schemaCompiler struct {
+	root interface{}
+	refs map[string]*schemaNode
+}
+
+func (c *schemaCompiler) errorf(loc, format string, a ...interface{}) error {
+	return fmt.Errorf("kitgo: %w: %s: %s", ErrSchemaInvalid, loc, fmt.Sprintf(format, a...))
+}
+
+func (c *schemaCompiler) compile(v interface{}, loc string) (*schemaNode, error) {
+	n := &schemaNode{loc: loc, minLength: -1, maxLength: -1, minItems: -1, maxItems: -1, minProperties: -1, maxProperties: -1}
+	if b, ok := v.(bool); ok {
+		n.always = &b
+		return n, nil
+	}
+	m, ok := toMap(v)
+	if !ok {
+		return nil, c.errorf(loc, "should be an object or a boolean")
+	}
+	var err error
+	if t, ok := m["type"]; ok {
+		if n.types, err = c.strings(t, loc+"/type"); err != nil {
+			return nil, err
+		}
+		for _, typ := range n.types {
+			switch typ {
+			case "null", "boolean", "object", "array", "number", "integer", "string":
+			default:
+				return nil, c.errorf(loc+"/type", "unknown type %q", typ)
+			}
+		}
+	}
+	if e, ok := m["enum"]; ok {
+		if n.enum, ok = toSlice(e); !ok {
+			return nil, c.errorf(loc+"/enum", "should be an array")
+		}
+		n.hasEnum = true
+	}
+	n.cons, n.hasCons = m["const"]
+	if p, ok := m["pattern"]; ok {
+		s, ok := p.(string)
+		if !ok {
+			return nil, c.errorf(loc+"/pattern", "should be a string")
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return nil, c.errorf(loc+"/pattern", "%s", err)
+		}
+	}
+	for kw, dst := range map[string]**float64{
+		"minimum": &n.minimum, "maximum": &n.maximum,
+		"exclusiveMinimum": &n.exclusiveMinimum, "exclusiveMaximum": &n.exclusiveMaximum,
+	} {
+		if v, ok := m[kw]; ok {
+			f, ok := numberJSON(v)
+			if !ok {
+				return nil, c.errorf(loc+"/"+kw, "should be a number")
+			}
+			*dst = &f
+		}
+	}
+	for kw, dst := range map[string]*int{
+		"minLength": &n.minLength, "maxLength": &n.maxLength,
+		"minItems": &n.minItems, "maxItems": &n.maxItems,
+		"minProperties": &n.minProperties, "maxProperties": &n.maxProperties,
+	} {
+		if v, ok := m[kw]; ok {
+			f, ok := numberJSON(v)
+			if !ok || f < 0 || f != math.Trunc(f) {
+				return nil, c.errorf(loc+"/"+kw, "should be a non-negative integer")
+			}
+			*dst = int(f)
+		}
+	}
+	if r, ok := m["required"]; ok {
+		if n.required, err = c.strings(r, loc+"/required"); err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := m["properties"]; ok {
+		props, ok := toMap(p)
+		if !ok {
+			return nil, c.errorf(loc+"/properties", "should be an object")
+		}
+		n.properties = make(map[string]*schemaNode, len(props))
+		for k := range props {
+			if n.properties[k], err = c.compile(props[k], schemaPointer(loc+"/properties", k)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for kw, dst := range map[string]**schemaNode{"additionalProperties": &n.additionalProperties, "items": &n.items} {
+		if v, ok := m[kw]; ok {
+			if *dst, err = c.compile(v, loc+"/"+kw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for kw, dst := range map[string]*[]*schemaNode{"allOf": &n.allOf, "anyOf": &n.anyOf, "oneOf": &n.oneOf} {
+		if v, ok := m[kw]; ok {
+			s, ok := toSlice(v)
+			if !ok || len(s) < 1 {
+				return nil, c.errorf(loc+"/"+kw, "should be a non-empty array")
+			}
+			*dst = make([]*schemaNode, len(s))
+			for i := range s {
+				if (*dst)[i], err = c.compile(s[i], loc+"/"+kw+"/"+strconv.Itoa(i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if r, ok := m["$ref"]; ok {
+		s, ok := r.(string)
+		if !ok {
+			return nil, c.errorf(loc+"/$ref", "should be a string")
+		}
+		if n.ref, err = c.resolve(s, loc+"/$ref"); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// resolve compile the target of a $ref once, the node is registered before
+// being compiled so a recursive schema refer to itself
+func (c *schemaCompiler) resolve(ref, loc string) (*schemaNode, error) {
+	if n, ok := c.refs[ref]; ok {
+		return n, nil
+	}
+	if !strings.HasPrefix(ref, "#") || (len(ref) > 1 && ref[1] != '/') {
+		return nil, c.errorf(loc, "unsupported reference %q", ref)
+	}
+	p, err := parsePath(ref[1:])
+	if err != nil {
+		return nil, c.errorf(loc, "%s", err)
+	}
+	v, err := p.get(c.root)
+	if err != nil {
+		return nil, c.errorf(loc, "%s", err)
+	}
+	n := new(schemaNode)
+	c.refs[ref] = n
+	compiled, err := c.compile(v, ref)
+	if err != nil {
+		return nil, err
+	}
+	*n = *compiled
+	return n, nil
+}
+
+// cycles reject a loop of $ref, allOf, anyOf or oneOf, each of them apply to
+// the same value so validating it would never end, e.g. {"$ref": "#"}, while a
+// loop through properties, additionalProperties or items is fine
+func (c *schemaCompiler) cycles(root *schemaNode) error {
+	const visiting, visited = 1, 2
+	state, seen := map[*schemaNode]int{}, map[*schemaNode]bool{}
+	var loop func(n *schemaNode) *schemaNode
+	loop = func(n *schemaNode) *schemaNode {
+		switch state[n] {
+		case visiting:
+			return n
+		case visited:
+			return nil
+		}
+		state[n] = visiting
+		for _, next := range n.applied() {
+			if l := loop(next); l != nil {
+				return l
+			}
+		}
+		state[n] = visited
+		return nil
+	}
+	queue := []*schemaNode{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == nil || seen[n] {
+			continue
+		}
+		seen[n] = true
+		if l := loop(n); l != nil {
+			return c.errorf(l.loc, "reference cycle on the same value")
+		}
+		queue = append(append(queue, n.applied()...), n.items, n.additionalProperties)
+		for _, p := range n.properties {
+			queue = append(queue, p)
+		}
+	}
+	return nil
+}
+
+func (c *schemaCompiler) strings(v interface{}, loc string) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return []string{s}, nil
+	}
+	l, ok := toSlice(v)
+	res := make([]string, len(l))
+	for i := 0; ok && i < len(l); i++ {
+		res[i], ok = l[i].(string)
+	}
+	if !ok {
+		return nil, c.errorf(loc, "should be a string or an array of string")
+	}
+	return res, nil
+}
+
+// applied lists the subschemas applied to the same value as n
+func (n *schemaNode) applied() []*schemaNode {
+	l := append(append(append([]*schemaNode{}, n.allOf...), n.anyOf...), n.oneOf...)
+	if n.ref != nil {
+		l = append(l, n.ref)
+	}
+	return l
+}
+
+func (n *schemaNode) fail(path, kw, format string, a ...interface{}) *Error {
+	return &Error{Code: kw, Path: path, Message: fmt.Sprintf(format, a...), Meta: Dict{"schema": n.loc + "/" + kw}}
+}
+
+func (n *schemaNode) validate(v interface{}, path string) (errs errorList) {
+	if n.always != nil {
+		if !*n.always {
+			return errs.Append(&Error{Code: "false", Path: path, Message: "is not allowed", Meta: Dict{"schema": n.loc}})
+		}
+		return nil
+	}
+	if n.ref != nil {
+		errs = errs.Append(n.ref.validate(v, path)...)
+	}
+	if len(n.types) > 0 && !n.is(v) {
+		return errs.Append(n.fail(path, "type", "should be %s, got %s", strings.Join(n.types, " or "), schemaType(v)))
+	}
+	if n.hasEnum {
+		found := false
+		for i := 0; i < len(n.enum) && !found; i++ {
+			found = equalJSON(v, n.enum[i])
+		}
+		if !found {
+			errs = errs.Append(n.fail(path, "enum", "should be one of %s", schemaString(n.enum)))
+		}
+	}
+	if n.hasCons && !equalJSON(v, n.cons) {
+		errs = errs.Append(n.fail(path, "const", "should be %s", schemaString(n.cons)))
+	}
+	if f, ok := numberJSON(v); ok {
+		errs = errs.Append(n.number(f, path)...)
+	}
+	if s, ok := v.(string); ok {
+		l := utf8.RuneCountInString(s)
+		if n.minLength >= 0 && l < n.minLength {
+			errs = errs.Append(n.fail(path, "minLength", "should have at least %d characters", n.minLength))
+		}
+		if n.maxLength >= 0 && l > n.maxLength {
+			errs = errs.Append(n.fail(path, "maxLength", "should have at most %d characters", n.maxLength))
+		}
+		if n.pattern != nil && !n.pattern.MatchString(s) {
+			errs = errs.Append(n.fail(path, "pattern", "should match %q", n.pattern))
+		}
+	}
+	if l, ok := toSlice(v); ok {
+		errs = errs.Append(n.array(l, path)...)
+	}
+	if m, ok := toMap(v); ok {
+		errs = errs.Append(n.object(m, path)...)
+	}
+	for i := range n.allOf {
+		errs = errs.Append(n.allOf[i].validate(v, path)...)
+	}
+	if len(n.anyOf) > 0 {
+		matched := false
+		for i := 0; i < len(n.anyOf) && !matched; i++ {
+			matched = len(n.anyOf[i].validate(v, path)) == 0
+		}
+		if !matched {
+			errs = errs.Append(n.fail(path, "anyOf", "should match at least one schema"))
+		}
+	}
+	if len(n.oneOf) > 0 {
+		matched := 0
+		for i := range n.oneOf {
+			if len(n.oneOf[i].validate(v, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			errs = errs.Append(n.fail(path, "oneOf", "should match exactly one schema, matched %d", matched))
+		}
+	}
+	return errs
+}
+
+func (n *schemaNode) is(v interface{}) bool {
+	typ := schemaType(v)
+	for _, t := range n.types {
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *schemaNode) number(f float64, path string) (errs errorList) {
+	if n.minimum != nil && f < *n.minimum {
+		errs = errs.Append(n.fail(path, "minimum", "should be >= %v", *n.minimum))
+	}
+	if n.maximum != nil && f > *n.maximum {
+		errs = errs.Append(n.fail(path, "maximum", "should be <= %v", *n.maximum))
+	}
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		errs = errs.Append(n.fail(path, "exclusiveMinimum", "should be > %v", *n.exclusiveMinimum))
+	}
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		errs = errs.Append(n.fail(path, "exclusiveMaximum", "should be < %v", *n.exclusiveMaximum))
+	}
+	return errs
+}
+
+func (n *schemaNode) array(l List, path string) (errs errorList) {
+	if n.minItems >= 0 && len(l) < n.minItems {
+		errs = errs.Append(n.fail(path, "minItems", "should have at least %d items", n.minItems))
+	}
+	if n.maxItems >= 0 && len(l) > n.maxItems {
+		errs = errs.Append(n.fail(path, "maxItems", "should have at most %d items", n.maxItems))
+	}
+	for i := 0; n.items != nil && i < len(l); i++ {
+		errs = errs.Append(n.items.validate(l[i], path+"/"+strconv.Itoa(i))...)
+	}
+	return errs
+}
+
+func (n *schemaNode) object(m Dict, path string) (errs errorList) {
+	if n.minProperties >= 0 && len(m) < n.minProperties {
+		errs = errs.Append(n.fail(path, "minProperties", "should have at least %d properties", n.minProperties))
+	}
+	if n.maxProperties >= 0 && len(m) > n.maxProperties {
+		errs = errs.Append(n.fail(path, "maxProperties", "should have at most %d properties", n.maxProperties))
+	}
+	for _, k := range n.required {
+		if _, ok := m[k]; !ok {
+			errs = errs.Append(n.fail(schemaPointer(path, k), "required", "is required"))
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if p, ok := n.properties[k]; ok {
+			errs = errs.Append(p.validate(m[k], schemaPointer(path, k))...)
+		} else if n.additionalProperties != nil {
+			errs = errs.Append(n.additionalProperties.validate(m[k], schemaPointer(path, k))...)
+		}
+	}
+	return errs
+}
+
+// schemaType return the JSON type of v, a number without fraction is
+// "integer"
+func schemaType(v interface{}) string {
+	if f, ok := numberJSON(v); ok {
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	if _, ok := toMap(v); ok {
+		return "object"
+	}
+	if _, ok := toSlice(v); ok {
+		return "array"
+	}
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// schemaString format v as JSON for error message
+func schemaString(v interface{}) string {
+	b, err := JSON.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// schemaPointer append an escaped key to a JSON Pointer
+func schemaPointer(path, key string) string {
+	return path + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
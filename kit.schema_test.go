@@ -0,0 +1,176 @@
+package kitgo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hokonco/kitgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test_pkg_schema(t *testing.T) {
+	t.Parallel()
+	Expect := NewWithT(t).Expect
+
+	user := kitgo.Schema.MustCompile([]byte(`{
+		"type": "object",
+		"required": ["name", "age"],
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 8, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 18, "maximum": 99},
+			"score": {"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1},
+			"role": {"enum": ["admin", "user", null]},
+			"kind": {"const": "user"},
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+			"meta": {"type": "object", "minProperties": 1, "maxProperties": 1},
+			"a/b": {"type": ["string", "null"]},
+			"friend": {"$ref": "#"},
+			"address": {"$ref": "#/$defs/address"}
+		},
+		"additionalProperties": false,
+		"$defs": {
+			"address": {
+				"type": "object",
+				"required": ["city"],
+				"properties": {"city": {"type": "string"}},
+				"additionalProperties": true
+			}
+		}
+	}`))
+	codes := func(err error) map[string]string {
+		m := map[string]string{}
+		var list interface{ Unwrap() []error }
+		Expect(errors.As(err, &list)).To(BeTrue())
+		for _, err := range list.Unwrap() {
+			e := err.(*kitgo.Error)
+			m[e.Path] += e.Code + ","
+		}
+		return m
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		Expect(user.Validate(kitgo.Dict{"name": "bob", "age": 18})).To(Succeed())
+		Expect(user.Validate(map[string]interface{}{
+			"name": "alice", "age": 99.0, "score": 0.5, "role": nil, "kind": "user",
+			"tags": kitgo.List{"a", "b"}, "meta": kitgo.Dict{"x": 1}, "a/b": nil,
+			"friend":  kitgo.Dict{"name": "bob", "age": int64(20)},
+			"address": kitgo.Dict{"city": "x", "zip": 1},
+		})).To(Succeed())
+		Expect(user.ValidateJSON([]byte(`{"name":"bob","age":30,"friend":{"name":"al","age":40}}`))).To(Succeed())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		err := user.ValidateJSON([]byte(`{
+			"name": "B", "age": 17.5, "score": 1, "role": "root", "kind": "admin",
+			"tags": [1, "a", "b"], "meta": {}, "a/b": 1, "extra": true,
+			"friend": {"name": "bob"}, "address": {}
+		}`))
+		Expect(codes(err)).To(Equal(map[string]string{
+			"/name":         "minLength,pattern,",
+			"/age":          "type,",
+			"/score":        "exclusiveMaximum,",
+			"/role":         "enum,",
+			"/kind":         "const,",
+			"/tags":         "maxItems,",
+			"/tags/0":       "type,",
+			"/meta":         "minProperties,",
+			"/a~1b":         "type,",
+			"/extra":        "false,",
+			"/friend/age":   "required,",
+			"/address/city": "required,",
+		}))
+		var e *kitgo.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Code).To(Equal("type"))
+		Expect(e.Path).To(Equal("/a~1b"))
+		Expect(e.Meta).To(Equal(kitgo.Dict{"schema": "#/properties/a~1b/type"}))
+		Expect(e.Error()).To(Equal("/a~1b: should be string or null, got integer"))
+		Expect(errors.Is(err, &kitgo.Error{Code: "required"})).To(BeTrue())
+
+		for v, msg := range map[interface{}]string{
+			"x":   ": should be object, got string",
+			nil:   ": should be object, got null",
+			true:  ": should be object, got boolean",
+			1.5:   ": should be object, got number",
+			"":    ": should be object, got string",
+			0:     ": should be object, got integer",
+			"ABC": ": should be object, got string",
+		} {
+			Expect(user.Validate(v)).To(MatchError(msg[2:]), msg)
+		}
+		Expect(user.Validate(kitgo.List{})).To(MatchError("should be object, got array"))
+		Expect(user.Validate(struct{}{})).To(MatchError("should be object, got struct {}"))
+
+		Expect(codes(user.Validate(kitgo.Dict{"name": "abcdefghi", "age": 100, "score": 0, "tags": kitgo.List{}, "meta": kitgo.Dict{"a": 1, "b": 2}}))).To(Equal(map[string]string{
+			"/name":  "maxLength,",
+			"/age":   "maximum,",
+			"/score": "exclusiveMinimum,",
+			"/tags":  "minItems,",
+			"/meta":  "maxProperties,",
+		}))
+
+		err = user.ValidateJSON([]byte(`{`))
+		Expect(errors.Is(err, &kitgo.Error{Code: "json"})).To(BeTrue())
+		Expect(err).To(MatchError("invalid JSON"))
+	})
+	t.Run("combinators", func(t *testing.T) {
+		schema, err := kitgo.Schema.Compile(kitgo.Dict{
+			"allOf": kitgo.List{kitgo.Dict{"minimum": 0}, kitgo.Dict{"maximum": 10}},
+			"anyOf": kitgo.List{kitgo.Dict{"type": "integer"}, kitgo.Dict{"minimum": 5}},
+			"oneOf": kitgo.List{kitgo.Dict{"maximum": 8}, kitgo.Dict{"minimum": 3}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schema.Validate(2)).To(Succeed())
+		Expect(schema.Validate(9.5)).To(Succeed())
+		// keywords of another type do not apply
+		Expect(schema.Validate("x")).To(MatchError("should match exactly one schema, matched 2"))
+		Expect(codes(schema.Validate(-1))).To(Equal(map[string]string{"": "minimum,"}))
+		Expect(codes(schema.Validate(2.5))).To(Equal(map[string]string{"": "anyOf,"}))
+		Expect(schema.Validate(4)).To(MatchError("should match exactly one schema, matched 2"))
+		Expect(codes(schema.Validate(11))).To(Equal(map[string]string{"": "maximum,"}))
+
+		always, never := kitgo.Schema.MustCompile(true), kitgo.Schema.MustCompile([]byte(`false`))
+		Expect(always.Validate(kitgo.Dict{})).To(Succeed())
+		Expect(never.Validate(nil)).To(MatchError("is not allowed"))
+		Expect(kitgo.Schema.MustCompile(kitgo.Dict{"const": complex(1, 0)}).Validate(1)).To(MatchError("should be (1+0i)"))
+		Expect(kitgo.Schema.MustCompile(json.RawMessage(`{"items": false}`)).Validate([]interface{}{1})).To(MatchError("/0: is not allowed"))
+	})
+	t.Run("Compile", func(t *testing.T) {
+		for schema, msg := range map[string]string{
+			`{`:                        "kitgo: invalid schema: ",
+			`1`:                        "kitgo: invalid schema: #: should be an object or a boolean",
+			`{"type": "int"}`:          `kitgo: invalid schema: #/type: unknown type "int"`,
+			`{"type": [1]}`:            "kitgo: invalid schema: #/type: should be a string or an array of string",
+			`{"enum": 1}`:              "kitgo: invalid schema: #/enum: should be an array",
+			`{"pattern": 1}`:           "kitgo: invalid schema: #/pattern: should be a string",
+			`{"pattern": "("}`:         "kitgo: invalid schema: #/pattern: error parsing regexp",
+			`{"minimum": "1"}`:         "kitgo: invalid schema: #/minimum: should be a number",
+			`{"minLength": -1}`:        "kitgo: invalid schema: #/minLength: should be a non-negative integer",
+			`{"required": "a"}`:        "",
+			`{"required": {}}`:         "kitgo: invalid schema: #/required: should be a string or an array of string",
+			`{"properties": []}`:       "kitgo: invalid schema: #/properties: should be an object",
+			`{"properties": {"a": 1}}`: "kitgo: invalid schema: #/properties/a: should be an object or a boolean",
+			`{"items": 1}`:             "kitgo: invalid schema: #/items: should be an object or a boolean",
+			`{"anyOf": []}`:            "kitgo: invalid schema: #/anyOf: should be a non-empty array",
+			`{"oneOf": [1]}`:           "kitgo: invalid schema: #/oneOf/0: should be an object or a boolean",
+			`{"$ref": 1}`:              "kitgo: invalid schema: #/$ref: should be a string",
+			`{"$ref": "other.json"}`:   `kitgo: invalid schema: #/$ref: unsupported reference "other.json"`,
+			`{"$ref": "#anchor"}`:      `kitgo: invalid schema: #/$ref: unsupported reference "#anchor"`,
+			`{"$ref": "#/$defs/none"}`: "kitgo: invalid schema: #/$ref: kitgo: path",
+			`{"$ref": "#/a~"}`:         "kitgo: invalid schema: #/$ref: kitgo: path",
+			`{"$ref": "#/$defs/a", "$defs": {"a": 1}}`: "kitgo: invalid schema: #/$defs/a: should be an object or a boolean",
+			`{"$ref": "#"}`: "kitgo: invalid schema: #: reference cycle on the same value",
+			`{"$ref": "#/$defs/a", "$defs": {"a": {"$ref": "#/$defs/a"}}}`:                                                                  "kitgo: invalid schema: #/$defs/a: reference cycle on the same value",
+			`{"items": {"$ref": "#/$defs/a"}, "$defs": {"a": {"allOf": [{"$ref": "#/$defs/b"}]}, "b": {"anyOf": [{"$ref": "#/$defs/a"}]}}}`: "kitgo: invalid schema: #/$defs/a: reference cycle on the same value",
+		} {
+			_, err := kitgo.Schema.Compile([]byte(schema))
+			if msg == "" {
+				Expect(err).NotTo(HaveOccurred(), schema)
+				continue
+			}
+			Expect(errors.Is(err, kitgo.ErrSchemaInvalid)).To(BeTrue(), schema)
+			Expect(err.Error()).To(HavePrefix(msg), schema)
+		}
+		Expect(func() { kitgo.Schema.MustCompile(1) }).To(Panic())
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"errors"
 	"io"
+	"strings"
 
 	"github.com/andybalholm/brotli"
 	"github.com/tdewolff/minify/v2"
@@ -46,6 +47,7 @@ func (compress_) New() *Client {
 				min.AddFunc("application/json", json.Minify)
 				min.AddFunc("application/ld+json", json.Minify)
 				min.AddFunc("application/javascript", js.Minify)
+				min.AddFunc("text/javascript", js.Minify)
 				return min
 			}(),
 			"",
@@ -132,7 +134,16 @@ func (x *Gzip) Write(dst io.Writer, src io.Reader) (err error) {
 	}
 	return
 }
-func (x *Minify) WithMediaType(mediatype string) *Minify { x.mediatype = mediatype; return x }
+
+// WithMediaType set the media type of the next Write, an extension such as
+// ".js" is resolved to the media type registered on MIME
+func (x *Minify) WithMediaType(mediatype string) *Minify {
+	if strings.HasPrefix(mediatype, ".") {
+		mediatype = MIME.TypeByExtension(mediatype)
+	}
+	x.mediatype = mediatype
+	return x
+}
 func (x *Minify) Write(dst io.Writer, src io.Reader) (err error) {
 	if err = errors.New("invalid parameter"); dst != nil && src != nil && x.mediatype != "" {
 		err = x.w.Minify(x.mediatype, dst, src)
@@ -12,6 +12,7 @@ import (
 	"net/http/httptrace"
 	"net/http/httputil"
 	"net/url"
+	"path/filepath"
 	"runtime/debug"
 	"sort"
 	"strings"
@@ -179,13 +180,7 @@ func (x HTTPServerWrapper) Run(onInfo func(string), onError func(error), shutdow
 		sig := ListenToSignal(syscall.SIGTERM, syscall.SIGKILL, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGSTOP, syscall.SIGTSTP)
 		errChan <- fmt.Errorf("signal: [%d] %s\n%s", sig, sig, debug.Stack())
 	}()
-	if x.Server.TLSConfig == nil {
-		go func() { errChan <- x.Server.ListenAndServe() }() // --> on srv error
-		onInfo(fmt.Sprintf("srv running on http://0.0.0.0%v", x.Server.Addr))
-	} else {
-		go func() { errChan <- x.Server.ListenAndServeTLS("", "") }() // --> on tls error
-		onInfo(fmt.Sprintf("tls running on https://0.0.0.0%v", x.Server.Addr))
-	}
+	go func() { errChan <- x.serve(onInfo) }() // --> on srv/tls error
 	onError(<-errChan)
 
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -195,6 +190,35 @@ func (x HTTPServerWrapper) Run(onInfo func(string), onError func(error), shutdow
 	return err
 }
 
+// Component return x as a LifecycleComponent, so that the signal & shutdown is
+// coordinated with the other components by LifecycleWrapper.Run instead, the
+// Timeout of the component bound the graceful shutdown
+func (x HTTPServerWrapper) Component(name string, onInfo func(string)) LifecycleComponent {
+	if onInfo == nil {
+		onInfo = func(string) {}
+	}
+	return LifecycleComponent{
+		Name: name,
+		Run: func(context.Context) error {
+			if err := x.serve(onInfo); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		},
+		Stop: x.Server.Shutdown,
+	}
+}
+
+// serve block on `ListenAndServe` or `ListenAndServeTLS` given the TLSConfig
+func (x HTTPServerWrapper) serve(onInfo func(string)) error {
+	if x.Server.TLSConfig == nil {
+		onInfo(fmt.Sprintf("srv running on http://0.0.0.0%v", x.Server.Addr))
+		return x.Server.ListenAndServe()
+	}
+	onInfo(fmt.Sprintf("tls running on https://0.0.0.0%v", x.Server.Addr))
+	return x.Server.ListenAndServeTLS("", "")
+}
+
 // =============================================================================
 // HANDLER
 // =============================================================================
@@ -234,9 +258,16 @@ func (httpHandler_) ServeContent(name string, modtime time.Time, content io.Read
 	})
 }
 
-// ServeFile is alias of http.ServeFile
+// ServeFile is alias of http.ServeFile, the Content-Type is set from the
+// extension registered on MIME unless it has been set before
 func (httpHandler_) ServeFile(dir string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if typ := MIME.TypeByExtension(filepath.Ext(dir)); typ != "" && w.Header().Get(ContentType) == "" {
+			if strings.HasPrefix(typ, "text/") {
+				typ += "; charset=utf-8"
+			}
+			w.Header().Set(ContentType, typ)
+		}
 		http.ServeFile(w, r, dir)
 	})
 }
@@ -275,7 +306,7 @@ func (httpHandler_) ResponseWith(state *responseState) http.Handler {
 		for i := 0; i < len(compression); i++ {
 			if compression[i] == "minify" {
 				if w.Header().Get(ContentType) == "" {
-					w.Header().Set(ContentType, http.DetectContentType(body))
+					w.Header().Set(ContentType, MIME.Detect(body))
 				}
 				b.Reset()
 				_ = c.Minify.WithMediaType(w.Header().Get(ContentType)).Write(b, read(body))
@@ -685,6 +716,10 @@ const (
 	ContentType         = "Content-Type"
 	ContentEncoding     = "Content-Encoding"
 	XContentTypeOptions = "X-Content-Type-Options"
+	RetryAfter          = "Retry-After"
+	RateLimitLimit      = "RateLimit-Limit"
+	RateLimitRemaining  = "RateLimit-Remaining"
+	RateLimitReset      = "RateLimit-Reset"
 )
 
 // Octet types from RFC 2616.
@@ -20,17 +20,17 @@ type smtp_ struct{}
 type SMTPConfig struct {
 	PlainAuthIdentity   string
 	PlainAuthUsername   string
-	PlainAuthPassword   string
+	PlainAuthPassword   Secret `config:",secret"`
 	CRAMMD5AuthUsername string
-	CRAMMD5AuthSecret   string
-	Addr                string
+	CRAMMD5AuthSecret   Secret `config:",secret"`
+	Addr                string `config:",required"`
 }
 
 func (x smtp_) New(conf *SMTPConfig) *NetSMTPWrapper {
 	PanicWhen(conf == nil || conf.Addr == "", conf)
-	auth := smtp.PlainAuth(conf.PlainAuthIdentity, conf.PlainAuthUsername, conf.PlainAuthPassword, conf.Addr)
+	auth := smtp.PlainAuth(conf.PlainAuthIdentity, conf.PlainAuthUsername, string(conf.PlainAuthPassword), conf.Addr)
 	if conf.CRAMMD5AuthUsername != "" && conf.CRAMMD5AuthSecret != "" {
-		auth = smtp.CRAMMD5Auth(conf.CRAMMD5AuthUsername, conf.CRAMMD5AuthSecret)
+		auth = smtp.CRAMMD5Auth(conf.CRAMMD5AuthUsername, string(conf.CRAMMD5AuthSecret))
 	}
 	return &NetSMTPWrapper{addr: conf.Addr, auth: auth}
 }
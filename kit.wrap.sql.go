@@ -17,7 +17,7 @@ var SQL sql_
 type sql_ struct{}
 
 func (sql_) New(conf *SQLConfig) *SQLWrapper {
-	s, err := sql.Open(conf.DriverName, conf.DataSourceName)
+	s, err := sql.Open(conf.DriverName, string(conf.DataSourceName))
 	PanicWhen(err != nil || s == nil, err)
 	s.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
 	s.SetConnMaxLifetime(conf.ConnMaxLifetime)
@@ -32,13 +32,14 @@ func (sql_) Test() (*SQLWrapper, *SQLMock) {
 }
 
 type SQLConfig struct {
-	DriverName      string
-	DataSourceName  string
+	DriverName      string `config:",required"`
+	DataSourceName  Secret `config:",required,secret"`
 	ConnMaxIdleTime time.Duration
 	ConnMaxLifetime time.Duration
 	MaxIdleConns    int
 	MaxOpenConns    int
 }
+
 type SQLWrapper struct {
 	*sql.DB
 	cache map[string]*sql.Stmt
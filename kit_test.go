@@ -2,6 +2,7 @@ package kitgo_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync/atomic"
@@ -201,6 +202,67 @@ func Test_pkg_errors(t *testing.T) {
 	err = errs.UnmarshalJSON([]byte(`["error","error"]`))
 	Expect(err).To(BeNil())
 	Expect(len(errs)).To(Equal(2))
+
+	t.Run("Is/As", func(t *testing.T) {
+		required := &kitgo.Error{Code: "required"}
+		cause := errors.New("cause")
+		errs := kitgo.NewErrors(
+			fmt.Errorf("wrapped: %w", cause),
+			&kitgo.Error{Code: "required", Path: "/name", Message: "name is required"},
+		)
+		Expect(errors.Is(errs, cause)).To(BeTrue())
+		Expect(errors.Is(errs, required)).To(BeTrue())
+		Expect(errors.Is(errs, &kitgo.Error{Code: "invalid"})).To(BeFalse())
+		Expect(errors.Is(errs, &kitgo.Error{})).To(BeFalse())
+		Expect(errors.Is(kitgo.NewErrors(cause), required)).To(BeFalse())
+
+		e := new(kitgo.Error)
+		Expect(errors.As(errs, &e)).To(BeTrue())
+		Expect(e.Path).To(Equal("/name"))
+		pe := new(kitgo.PathError)
+		Expect(errors.As(errs, &pe)).To(BeFalse())
+		Expect(errs.Unwrap()).To(Equal([]error(errs)))
+
+		Expect(errors.Is(&kitgo.Error{Err: cause}, cause)).To(BeTrue())
+	})
+	t.Run("Error", func(t *testing.T) {
+		Expect((&kitgo.Error{Code: "required"}).Error()).To(Equal("required"))
+		Expect((&kitgo.Error{Code: "required", Err: errors.New("cause")}).Error()).To(Equal("cause"))
+		Expect((&kitgo.Error{Code: "required", Path: "/a", Message: "a is required"}).Error()).To(Equal("/a: a is required"))
+	})
+	t.Run("JSON", func(t *testing.T) {
+		errs := kitgo.NewErrors(
+			errors.New("plain"),
+			&kitgo.Error{Code: "required", Path: "/name", Err: errors.New("name is required")},
+			&kitgo.Error{Message: "message only"},
+		)
+		b, err := errs.MarshalJSON()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`["plain",{"code":"required","path":"/name","message":"name is required"},{"message":"message only"}]`))
+
+		errs = kitgo.NewErrors()
+		Expect(errs.UnmarshalJSON([]byte(`["plain","",{"code":"max","path":"/age","message":"too old","meta":{"max":99}}]`))).To(Succeed())
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Error()).To(Equal("plain"))
+		Expect(errs[1]).To(Equal(&kitgo.Error{Code: "max", Path: "/age", Message: "too old", Meta: kitgo.Dict{"max": float64(99)}}))
+
+		// round trip, Meta included
+		b, err = errs.MarshalJSON()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`["plain",{"code":"max","path":"/age","message":"too old","meta":{"max":99}}]`))
+		var back kitgo.Error
+		Expect(back.UnmarshalJSON([]byte(`{"code":"max","meta":{"max":99}}`))).To(Succeed())
+		Expect(back).To(Equal(kitgo.Error{Code: "max", Meta: kitgo.Dict{"max": float64(99)}}))
+		Expect(back.UnmarshalJSON([]byte(`{"code":"max"}`))).To(Succeed())
+		Expect(back.Meta).To(BeNil())
+
+		// a malformed element leaves the list untouched
+		Expect(errs.UnmarshalJSON([]byte(`["other",{"code":1}]`))).To(HaveOccurred())
+		Expect(errs.UnmarshalJSON([]byte(`["other",1]`))).To(HaveOccurred())
+		Expect(errs.UnmarshalJSON([]byte(`{`))).To(HaveOccurred())
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Error()).To(Equal("plain"))
+	})
 }
 
 func Test_pkg_currency(t *testing.T) {
@@ -580,6 +642,13 @@ func Test_pkg_currency(t *testing.T) {
 		}
 		Expect(err).To(BeNil())
 		Expect(string(b)).To(Equal(tests[i].expect))
+
+		// round-trip, Value is rounded to the precision of the currency
+		c := kitgo.Currency{Tag: tests[i].parameter.Tag, Format: tests[i].parameter.Format}
+		Expect(c.UnmarshalJSON(b)).To(Succeed(), tests[i].parameter.Tag)
+		m, err := tests[i].parameter.Money()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Value).To(Equal(m.Currency().Value), tests[i].parameter.Tag)
 	}
 }
 